@@ -0,0 +1,87 @@
+package pool_test
+
+import (
+	"testing"
+	"time"
+
+	pool "github.com/posidoni/resource-pool"
+)
+
+func newBenchPool(max int64) *pool.Pool[*int] {
+	return pool.New(
+		max,
+		time.Minute,
+		func() (*int, error) {
+			return new(int), nil
+		},
+		func(r *int) {},
+		true,
+	)
+}
+
+func BenchmarkGetPut(b *testing.B) {
+	p := newBenchPool(1)
+	defer p.Close()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		r, _ := p.Get()
+		p.Put(r)
+	}
+}
+
+// More callers than resources, so that some of them wait.
+func BenchmarkGetPutContended(b *testing.B) {
+	p := newBenchPool(64)
+	defer p.Close()
+	benchContended(b, p)
+}
+
+// Same load and capacity as BenchmarkGetPutContended, so that the two compare
+// single lock against 8 shards; the difference shows with -cpu of 8 and more.
+func BenchmarkShardedGetPutContended(b *testing.B) {
+	p := pool.NewSharded(
+		8,
+		func() (*int, error) {
+			return new(int), nil
+		},
+		func(r *int) {},
+		pool.WithMax[*int](64),
+		pool.WithWait[*int](time.Minute),
+	)
+	defer p.Close()
+	benchContended(b, p)
+}
+
+func benchContended(b *testing.B, p pool.Pooler[*int]) {
+	b.ReportAllocs()
+	b.SetParallelism(16)
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			r, err := p.Get()
+			if err != nil {
+				b.Error(err)
+				return
+			}
+			p.Put(r)
+		}
+	})
+}
+
+// Idle resources are never kept, so that every Get takes the slow path.
+func BenchmarkGetPutNoIdle(b *testing.B) {
+	p := pool.NewUnlimited(
+		func() (*int, error) {
+			return new(int), nil
+		},
+		func(r *int) {},
+		pool.WithMaxIdle[*int](0),
+	)
+	defer p.Close()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		r, _ := p.Get()
+		p.Put(r)
+	}
+}
@@ -0,0 +1,62 @@
+package pool
+
+import (
+	"sync"
+	"time"
+)
+
+// Circuit breaker suspending construction after consecutive constructor
+// failures, see WithCircuitBreaker.
+type breaker struct {
+	m sync.Mutex
+	// Times of consecutive failures, most recent last.
+	failures []time.Time
+	// Construction is suspended until then, zero if circuit is closed.
+	openUntil time.Time
+	// Whether constructor is being probed after suspension.
+	probing bool
+}
+
+// Reports whether constructor may be called now. Once suspension is over,
+// only one caller is let through to probe constructor.
+func (b *breaker) allow(now time.Time) bool {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	if b.openUntil.IsZero() {
+		return true
+	}
+	if now.Before(b.openUntil) || b.probing {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+// Records outcome of constructor call, opening circuit for cooldown after
+// threshold consecutive failures within window or after failed probe.
+func (b *breaker) record(now time.Time, err error, threshold int, window, cooldown time.Duration) {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	if err == nil {
+		b.failures = b.failures[:0]
+		b.openUntil = time.Time{}
+		b.probing = false
+		return
+	}
+	if b.probing {
+		b.probing = false
+		b.openUntil = now.Add(cooldown)
+		return
+	}
+
+	b.failures = append(b.failures, now)
+	if len(b.failures) > threshold {
+		b.failures = b.failures[len(b.failures)-threshold:]
+	}
+	if len(b.failures) == threshold && now.Sub(b.failures[0]) <= window {
+		b.failures = b.failures[:0]
+		b.openUntil = now.Add(cooldown)
+	}
+}
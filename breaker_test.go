@@ -0,0 +1,126 @@
+package pool_test
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pool "github.com/posidoni/resource-pool"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	t.Parallel()
+	errDown := errors.New("backend is down")
+
+	t.Run(
+		"When CTR fails repeatedly, Get fails fast until cooldown is over",
+		func(t *testing.T) {
+			t.Parallel()
+			clock := newFakeClock()
+			ctrCalls := int64(0)
+			p := pool.NewWithOptions(
+				func() (*int, error) {
+					atomic.AddInt64(&ctrCalls, 1)
+					return nil, errDown
+				},
+				func(r *int) {},
+				pool.WithClock[*int](clock),
+				pool.WithCircuitBreaker[*int](3, time.Minute, 10*time.Second),
+			)
+			for i := 0; i < 3; i++ {
+				_, err := p.Get()
+				require.ErrorIs(t, err, errDown)
+			}
+
+			_, err := p.Get()
+			require.ErrorIs(t, err, pool.ErrCircuitOpen)
+			require.Equal(t, int64(3), atomic.LoadInt64(&ctrCalls))
+
+			clock.Advance(10 * time.Second)
+			_, err = p.Get()
+			require.ErrorIs(t, err, errDown, "probe is let through after cooldown")
+			require.Equal(t, int64(4), atomic.LoadInt64(&ctrCalls))
+
+			_, err = p.Get()
+			require.ErrorIs(t, err, pool.ErrCircuitOpen, "failed probe suspends construction again")
+			require.Equal(t, int64(4), atomic.LoadInt64(&ctrCalls))
+		})
+
+	t.Run(
+		"When probe succeeds, construction resumes",
+		func(t *testing.T) {
+			t.Parallel()
+			clock := newFakeClock()
+			down := atomic.Bool{}
+			down.Store(true)
+			p := pool.NewWithOptions(
+				func() (*int, error) {
+					if down.Load() {
+						return nil, errDown
+					}
+					return new(int), nil
+				},
+				func(r *int) {},
+				pool.WithClock[*int](clock),
+				pool.WithCircuitBreaker[*int](2, time.Minute, time.Second),
+			)
+			for i := 0; i < 2; i++ {
+				_, err := p.Get()
+				require.ErrorIs(t, err, errDown)
+			}
+			_, err := p.Get()
+			require.ErrorIs(t, err, pool.ErrCircuitOpen)
+
+			down.Store(false)
+			clock.Advance(time.Second)
+			for i := 0; i < 3; i++ {
+				r, err := p.Get()
+				require.NoError(t, err)
+				require.NotNil(t, r)
+			}
+		})
+
+	t.Run(
+		"When failures are spread wider than window, circuit stays closed",
+		func(t *testing.T) {
+			t.Parallel()
+			clock := newFakeClock()
+			p := pool.NewWithOptions(
+				func() (*int, error) { return nil, errDown },
+				func(r *int) {},
+				pool.WithClock[*int](clock),
+				pool.WithCircuitBreaker[*int](2, time.Second, time.Minute),
+			)
+			for i := 0; i < 4; i++ {
+				_, err := p.Get()
+				require.ErrorIs(t, err, errDown)
+				clock.Advance(2 * time.Second)
+			}
+		})
+
+	t.Run(
+		"When circuit is open, idle resources are still handed out",
+		func(t *testing.T) {
+			t.Parallel()
+			clock := newFakeClock()
+			p := pool.NewWithOptions(
+				func() (*int, error) { return nil, errDown },
+				func(r *int) {},
+				pool.WithClock[*int](clock),
+				pool.WithCircuitBreaker[*int](1, time.Minute, time.Minute),
+			)
+			_, err := p.Get()
+			require.ErrorIs(t, err, errDown)
+
+			idle := new(int)
+			require.True(t, p.Put(idle))
+			r, err := p.Get()
+			require.NoError(t, err)
+			require.Same(t, idle, r)
+
+			_, err = p.Get()
+			require.ErrorIs(t, err, pool.ErrCircuitOpen)
+		})
+}
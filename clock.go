@@ -0,0 +1,22 @@
+package pool
+
+import "time"
+
+// Clock tells time to the pool: ages of resources, waiting timeouts,
+// construction backoff and reaper sweeps are measured with it. Default one
+// is the real clock, tests may inject fake one with WithClock.
+type Clock interface {
+	Now() time.Time
+	// After is like time.After.
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
@@ -0,0 +1,129 @@
+package pool_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	pool "github.com/posidoni/resource-pool"
+	"github.com/stretchr/testify/require"
+)
+
+// Clock that moves only when told to.
+type fakeClock struct {
+	m       sync.Mutex
+	now     time.Time
+	waiters []fakeTimer
+}
+
+type fakeTimer struct {
+	at time.Time
+	c  chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Unix(0, 0)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.m.Lock()
+	defer c.m.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.m.Lock()
+	defer c.m.Unlock()
+	t := fakeTimer{at: c.now.Add(d), c: make(chan time.Time, 1)}
+	if d <= 0 {
+		t.c <- c.now
+		return t.c
+	}
+	c.waiters = append(c.waiters, t)
+	return t.c
+}
+
+// Moves clock forward by d, firing timers that are due.
+func (c *fakeClock) Advance(d time.Duration) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	c.now = c.now.Add(d)
+	pending := c.waiters[:0]
+	for _, t := range c.waiters {
+		if t.at.After(c.now) {
+			pending = append(pending, t)
+			continue
+		}
+		t.c <- c.now
+	}
+	c.waiters = pending
+}
+
+// Number of timers that haven't fired yet.
+func (c *fakeClock) Pending() int {
+	c.m.Lock()
+	defer c.m.Unlock()
+	return len(c.waiters)
+}
+
+func TestClock(t *testing.T) {
+	t.Parallel()
+
+	t.Run(
+		"When fake clock passes wait duration, waiting Get fails with ErrResourceUnavailable",
+		func(t *testing.T) {
+			t.Parallel()
+			clock := newFakeClock()
+			p := pool.New(
+				1,
+				time.Hour,
+				func() (*int, error) {
+					return new(int), nil
+				},
+				func(r *int) {},
+				true,
+				pool.WithClock[*int](clock),
+			)
+			_, _ = p.Get()
+
+			errs := make(chan error)
+			go func() {
+				_, err := p.Get()
+				errs <- err
+			}()
+			require.Eventually(t, func() bool { return clock.Pending() == 1 }, time.Second, time.Millisecond)
+
+			clock.Advance(time.Hour)
+			require.ErrorIs(t, <-errs, pool.ErrResourceUnavailable)
+			require.Equal(t, time.Hour, p.Stats().TimedOut.Max)
+		})
+
+	t.Run(
+		"When fake clock passes max idle time, idle object expires",
+		func(t *testing.T) {
+			t.Parallel()
+			clock := newFakeClock()
+			p := pool.New(
+				1,
+				time.Hour,
+				func() (*int, error) {
+					return new(int), nil
+				},
+				func(r *int) {},
+				true,
+				pool.WithClock[*int](clock),
+				pool.WithMaxIdleTime[*int](time.Hour),
+			)
+			r, _ := p.Get()
+			p.Put(r)
+
+			clock.Advance(59 * time.Minute)
+			got, _ := p.Get()
+			require.Same(t, r, got)
+			p.Put(got)
+
+			clock.Advance(time.Hour)
+			got, _ = p.Get()
+			require.NotSame(t, r, got)
+		})
+}
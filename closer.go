@@ -0,0 +1,27 @@
+package pool
+
+import "io"
+
+// CloserDestructor returns destructor that closes resource, for the common
+// case of resources implementing io.Closer. Close errors are dropped, use
+// NewCloserPool to have them reported by Close of the pool.
+func CloserDestructor[T io.Closer]() func(T) {
+	return func(resource T) {
+		_ = resource.Close()
+	}
+}
+
+// NewCloserPool creates new pool like NewWithOptions does, with destructor
+// that closes resource. Errors of resources closed by Close (or Cleanup) of
+// the pool are joined and returned from it, see WithDestructorErr.
+func NewCloserPool[T io.Closer](factoryFn func() (T, error), opts ...Option[T]) *Pool[T] {
+	return NewWithOptions(
+		factoryFn,
+		nil,
+		append([]Option[T]{
+			WithDestructorErr(func(resource T) error {
+				return resource.Close()
+			}),
+		}, opts...)...,
+	)
+}
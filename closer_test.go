@@ -0,0 +1,59 @@
+package pool_test
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pool "github.com/posidoni/resource-pool"
+	"github.com/stretchr/testify/require"
+)
+
+type closer struct {
+	closed atomic.Bool
+	err    error
+}
+
+func (c *closer) Close() error {
+	c.closed.Store(true)
+	return c.err
+}
+
+func TestCloser(t *testing.T) {
+	t.Parallel()
+
+	t.Run(
+		"When pool destructs closable resource, it is closed",
+		func(t *testing.T) {
+			t.Parallel()
+			p := pool.New(
+				1,
+				time.Millisecond,
+				func() (*closer, error) { return &closer{}, nil },
+				pool.CloserDestructor[*closer](),
+				false,
+			)
+			c, err := p.Get()
+			require.NoError(t, err)
+			require.True(t, p.Put(c))
+			require.NoError(t, p.Cleanup())
+			require.True(t, c.closed.Load())
+		})
+
+	t.Run(
+		"When closer pool is closed, Close errors are reported",
+		func(t *testing.T) {
+			t.Parallel()
+			errClose := errors.New("close failed")
+			p := pool.NewCloserPool(
+				func() (*closer, error) { return &closer{err: errClose}, nil },
+				pool.WithMax[*closer](1),
+			)
+			c, err := p.Get()
+			require.NoError(t, err)
+			require.True(t, p.Put(c))
+			require.ErrorIs(t, p.Cleanup(), errClose)
+			require.True(t, c.closed.Load())
+		})
+}
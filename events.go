@@ -0,0 +1,118 @@
+package pool
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// EventType tells what happened to the pool, see Events.
+type EventType int
+
+const (
+	// Resource was constructed.
+	EventCreated EventType = iota
+	// Resource was destructed.
+	EventDestroyed
+	// Resource was handed out by Get or its variants.
+	EventBorrowed
+	// Resource was accepted back by Put or its variants.
+	EventReturned
+	// Get gave up waiting for resource.
+	EventTimedOut
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventCreated:
+		return "created"
+	case EventDestroyed:
+		return "destroyed"
+	case EventBorrowed:
+		return "borrowed"
+	case EventReturned:
+		return "returned"
+	case EventTimedOut:
+		return "timed out"
+	default:
+		return "unknown"
+	}
+}
+
+// Event is a notification about pool activity.
+type Event struct {
+	Type EventType
+	// When event happened, according to pool's clock.
+	Time time.Time
+}
+
+// Number of events buffered for slow subscriber before they are dropped.
+const eventsBuffer = 128
+
+// Channel events are published to, created on first subscription.
+type events struct {
+	m sync.Mutex
+	c chan Event
+	// Whether Close closed the channel.
+	closed bool
+	// Fast path for publishing with no subscriber.
+	subscribed atomic.Bool
+	dropped    atomic.Int64
+}
+
+// Events returns channel pool publishes its activity to, so that caller can
+// react to it instead of polling Stats, e.g. to scale out. Every call returns
+// the same channel, so events are split between its readers. Pool never
+// blocks publishing: events subscriber is too slow to receive are dropped and
+// counted, see DroppedEvents. Events are published only once Events was
+// called. Channel is closed by Close (or Cleanup).
+func (pool *Pool[T]) Events() <-chan Event {
+	pool.events.m.Lock()
+	defer pool.events.m.Unlock()
+
+	if pool.events.c == nil {
+		pool.events.c = make(chan Event, eventsBuffer)
+		if pool.events.closed {
+			close(pool.events.c)
+		}
+		pool.events.subscribed.Store(true)
+	}
+	return pool.events.c
+}
+
+// DroppedEvents returns number of events dropped because subscriber of
+// Events didn't keep up.
+func (pool *Pool[T]) DroppedEvents() int64 {
+	return pool.events.dropped.Load()
+}
+
+// Publishes event of type t to subscriber, if there is one.
+func (pool *Pool[T]) publish(t EventType) {
+	if !pool.events.subscribed.Load() {
+		return
+	}
+
+	pool.events.m.Lock()
+	defer pool.events.m.Unlock()
+
+	if pool.events.closed {
+		return
+	}
+	select {
+	case pool.events.c <- Event{Type: t, Time: pool.clock.Now()}:
+	default:
+		pool.events.dropped.Add(1)
+	}
+}
+
+// Closes events channel, so that subscriber stops. Nothing is published
+// afterwards.
+func (pool *Pool[T]) closeEvents() {
+	pool.events.m.Lock()
+	defer pool.events.m.Unlock()
+
+	if pool.events.c != nil && !pool.events.closed {
+		close(pool.events.c)
+	}
+	pool.events.closed = true
+}
@@ -0,0 +1,101 @@
+package pool_test
+
+import (
+	"testing"
+	"time"
+
+	pool "github.com/posidoni/resource-pool"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvents(t *testing.T) {
+	t.Parallel()
+
+	newPool := func() *pool.Pool[*int] {
+		return pool.New(
+			1,
+			10*time.Millisecond,
+			func() (*int, error) { return new(int), nil },
+			func(r *int) {},
+			false,
+		)
+	}
+	types := func(events <-chan pool.Event) []pool.EventType {
+		ts := []pool.EventType{}
+		for e := range events {
+			ts = append(ts, e.Type)
+		}
+		return ts
+	}
+
+	t.Run(
+		"When subscriber listens, pool activity is published in order and channel is closed on Cleanup",
+		func(t *testing.T) {
+			t.Parallel()
+			p := newPool()
+			events := p.Events()
+
+			r, err := p.Get()
+			require.NoError(t, err)
+			_, err = p.Get()
+			require.ErrorIs(t, err, pool.ErrResourceUnavailable)
+			require.True(t, p.Put(r))
+			require.NoError(t, p.Cleanup())
+
+			require.Equal(t, []pool.EventType{
+				pool.EventCreated,
+				pool.EventBorrowed,
+				pool.EventTimedOut,
+				pool.EventReturned,
+				pool.EventDestroyed,
+			}, types(events))
+			require.Zero(t, p.DroppedEvents())
+		})
+
+	t.Run(
+		"When subscriber is slow, events are dropped and counted instead of blocking pool",
+		func(t *testing.T) {
+			t.Parallel()
+			p := newPool()
+			events := p.Events()
+			for i := 0; i < 200; i++ {
+				r, err := p.Get()
+				require.NoError(t, err)
+				require.True(t, p.Put(r))
+			}
+			require.NoError(t, p.Cleanup())
+
+			received := len(types(events))
+			require.Positive(t, p.DroppedEvents())
+			require.Equal(t, int64(402), int64(received)+p.DroppedEvents())
+		})
+
+	t.Run(
+		"When pool is closed before subscription, channel is closed right away",
+		func(t *testing.T) {
+			t.Parallel()
+			p := newPool()
+			require.NoError(t, p.Cleanup())
+			require.Empty(t, types(p.Events()))
+		})
+
+	t.Run(
+		"When events are published, they carry time of pool clock",
+		func(t *testing.T) {
+			t.Parallel()
+			clock := newFakeClock()
+			p := pool.NewWithOptions(
+				func() (*int, error) { return new(int), nil },
+				func(r *int) {},
+				pool.WithClock[*int](clock),
+			)
+			events := p.Events()
+			clock.Advance(time.Hour)
+			_, err := p.Get()
+			require.NoError(t, err)
+
+			e := <-events
+			require.Equal(t, pool.EventCreated, e.Type)
+			require.Equal(t, time.Unix(0, 0).Add(time.Hour), e.Time)
+		})
+}
@@ -0,0 +1,96 @@
+package pool
+
+import (
+	"time"
+)
+
+// EvictionPolicy decides which idle resource is handed out next and which
+// idle resources are destructed, see WithEvictionPolicy. Methods are called
+// while pool is locked, so they must be quick and must not call pool methods.
+type EvictionPolicy[T any] interface {
+	// Select returns index of idle resource to hand out. Idle resources are
+	// ordered from least to most recently returned, there is at least one.
+	// Slice is reused by pool, so it must not be retained. Index out of range
+	// selects the first one.
+	Select(idle []IdleResource[T]) int
+	// ShouldEvict reports whether resource must be destructed instead of being
+	// handed out or kept idle, now is according to pool's clock. It is
+	// consulted on Get and Put and periodically by reaper.
+	ShouldEvict(r IdleResource[T], now time.Time) bool
+}
+
+// IdleResource tells what pool knows about idle resource, see EvictionPolicy.
+type IdleResource[T any] struct {
+	Value T
+	// When resource was constructed by pool or first put into it.
+	CreatedAt time.Time
+	// When resource was put back last time.
+	ReturnedAt time.Time
+	// When resource was handed out last time, zero if it never was.
+	BorrowedAt time.Time
+	// Number of times resource has been handed out.
+	Uses int
+}
+
+// Built-in policy that hands out resource by its position among n idle ones
+// and never evicts, so pool needs neither to describe idle resources to it
+// nor to run reaper for it.
+type positional interface {
+	position(n int) int
+}
+
+// FIFO returns policy handing out least recently returned resource first,
+// which spreads load evenly across idle resources. It is the default.
+func FIFO[T any]() EvictionPolicy[T] {
+	return fifo[T]{}
+}
+
+// LIFO returns policy handing out most recently returned resource first, see
+// WithLIFO.
+func LIFO[T any]() EvictionPolicy[T] {
+	return lifo[T]{}
+}
+
+// LRU returns policy handing out least recently used resource first, i.e. the
+// one handed out longest ago, no matter how long it was borrowed for. Unlike
+// FIFO, resource borrowed for long doesn't lose its turn once returned.
+func LRU[T any]() EvictionPolicy[T] {
+	return lru[T]{}
+}
+
+type fifo[T any] struct{}
+
+func (fifo[T]) Select(idle []IdleResource[T]) int                 { return 0 }
+func (fifo[T]) ShouldEvict(r IdleResource[T], now time.Time) bool { return false }
+func (fifo[T]) position(n int) int                                { return 0 }
+
+type lifo[T any] struct{}
+
+func (lifo[T]) Select(idle []IdleResource[T]) int                 { return len(idle) - 1 }
+func (lifo[T]) ShouldEvict(r IdleResource[T], now time.Time) bool { return false }
+func (lifo[T]) position(n int) int                                { return n - 1 }
+
+type lru[T any] struct{}
+
+func (lru[T]) Select(idle []IdleResource[T]) int {
+	oldest := 0
+	for i, r := range idle {
+		if r.BorrowedAt.Before(idle[oldest].BorrowedAt) {
+			oldest = i
+		}
+	}
+	return oldest
+}
+
+func (lru[T]) ShouldEvict(r IdleResource[T], now time.Time) bool { return false }
+
+// Describes idle resource to eviction policy.
+func (e entry[T]) describe() IdleResource[T] {
+	return IdleResource[T]{
+		Value:      e.value,
+		CreatedAt:  e.createdAt,
+		ReturnedAt: e.idleSince,
+		BorrowedAt: e.lentAt,
+		Uses:       e.uses,
+	}
+}
@@ -0,0 +1,131 @@
+package pool_test
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pool "github.com/posidoni/resource-pool"
+	"github.com/stretchr/testify/require"
+)
+
+// Evicts resources idle for longer than maxIdle, unless it is zero, hands out
+// the most used one.
+type busiestPolicy struct {
+	maxIdle time.Duration
+}
+
+func (p busiestPolicy) Select(idle []pool.IdleResource[*int]) int {
+	busiest := 0
+	for i, r := range idle {
+		if r.Uses > idle[busiest].Uses {
+			busiest = i
+		}
+	}
+	return busiest
+}
+
+func (p busiestPolicy) ShouldEvict(r pool.IdleResource[*int], now time.Time) bool {
+	return p.maxIdle > 0 && now.Sub(r.ReturnedAt) > p.maxIdle
+}
+
+// Selects index out of range, as buggy policy might.
+type outOfRangePolicy struct{}
+
+func (outOfRangePolicy) Select(idle []pool.IdleResource[*int]) int { return len(idle) }
+
+func (outOfRangePolicy) ShouldEvict(r pool.IdleResource[*int], now time.Time) bool { return false }
+
+func TestEvictionPolicy(t *testing.T) {
+	t.Parallel()
+
+	t.Run(
+		"When policy is LRU, object handed out longest ago is handed out first",
+		func(t *testing.T) {
+			t.Parallel()
+			clock := newFakeClock()
+			p := pool.NewWithOptions(
+				func() (*int, error) { return new(int), nil },
+				func(r *int) {},
+				pool.WithClock[*int](clock),
+				pool.WithEvictionPolicy(pool.LRU[*int]()),
+			)
+			first, _ := p.Get()
+			clock.Advance(time.Second)
+			second, _ := p.Get()
+			p.Put(second)
+			p.Put(first)
+
+			r, _ := p.Get()
+			require.Same(t, first, r, "FIFO would hand out second one")
+		})
+
+	t.Run(
+		"When custom policy selects object, it is handed out regardless of order",
+		func(t *testing.T) {
+			t.Parallel()
+			p := pool.NewWithOptions(
+				func() (*int, error) { return new(int), nil },
+				func(r *int) {},
+				pool.WithEvictionPolicy[*int](busiestPolicy{}),
+			)
+			rs, _ := p.GetN(3)
+			p.Put(rs[1])
+			r, _ := p.Get()
+			require.Same(t, rs[1], r)
+			p.PutN([]*int{rs[0], rs[1], rs[2]})
+
+			r, _ = p.Get()
+			require.Same(t, rs[1], r, "Object used twice is the busiest")
+		})
+
+	t.Run(
+		"When custom policy evicts idle objects, reaper destructs them",
+		func(t *testing.T) {
+			t.Parallel()
+			clock := newFakeClock()
+			dstrCalls := int64(0)
+			p := pool.NewWithOptions(
+				func() (*int, error) { return new(int), nil },
+				func(r *int) { atomic.AddInt64(&dstrCalls, 1) },
+				pool.WithClock[*int](clock),
+				pool.WithEvictionPolicy[*int](busiestPolicy{maxIdle: time.Minute}),
+			)
+			defer p.Close()
+			rs, _ := p.GetN(2)
+			p.Put(rs[0])
+			require.Eventually(t, func() bool { return clock.Pending() == 1 }, time.Second, time.Millisecond)
+			clock.Advance(30 * time.Second)
+			p.Put(rs[1])
+			require.Zero(t, atomic.LoadInt64(&dstrCalls))
+
+			require.Eventually(t, func() bool { return clock.Pending() == 1 }, time.Second, time.Millisecond)
+			clock.Advance(40 * time.Second)
+			require.Eventually(t, func() bool {
+				return atomic.LoadInt64(&dstrCalls) == 1 && p.Idle() == 1
+			}, time.Second, time.Millisecond)
+
+			r, _ := p.Get()
+			require.Same(t, rs[1], r)
+		})
+
+	t.Run(
+		"When custom policy selects index out of range, least recently returned object is handed out",
+		func(t *testing.T) {
+			t.Parallel()
+			p := pool.NewWithOptions(
+				func() (*int, error) { return new(int), nil },
+				func(r *int) {},
+				pool.WithEvictionPolicy[*int](outOfRangePolicy{}),
+			)
+			rs, _ := p.GetN(2)
+			p.PutN(rs)
+
+			r, err := p.Get()
+			require.NoError(t, err)
+			require.Same(t, rs[0], r)
+			r, err = p.Get()
+			require.NoError(t, err)
+			require.Same(t, rs[1], r)
+		})
+}
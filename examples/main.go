@@ -21,9 +21,7 @@ func main() {
 		func() (*amqp.Channel, error) {
 			return conn.Channel() // <-- we are able to capture anything in constructor
 		},
-		func(c *amqp.Channel) {
-			c.Close() // <-- destructors are called for each resource that pool owns
-		},
+		pool.CloserDestructor[*amqp.Channel](), // <-- destructors are called for each resource that pool owns
 		true,
 	)
 
@@ -0,0 +1,58 @@
+// Package expvarpool publishes pool metrics with expvar, so that they show up
+// on /debug/vars.
+//
+// It lives in its own package, so that pool itself doesn't import expvar,
+// which registers its handler with http.DefaultServeMux.
+package expvarpool
+
+import (
+	"encoding/json"
+	"expvar"
+
+	pool "github.com/posidoni/resource-pool"
+)
+
+// StatsSource is anything that reports pool stats, e.g. *pool.Pool.
+type StatsSource interface {
+	Stats() pool.Stats
+}
+
+// Var is expvar.Var reporting metrics sourced from pool Stats as JSON map.
+type Var struct {
+	source StatsSource
+}
+
+// NewVar returns Var for the given pool, which is to be published by caller,
+// e.g. as part of expvar.Map.
+func NewVar(source StatsSource) *Var {
+	return &Var{source: source}
+}
+
+// Publish publishes Var for the given pool under name:
+//
+//	expvarpool.Publish("amqp", p)
+//
+// Like expvar.Publish, it panics if name is already taken.
+func Publish(name string, source StatsSource) *Var {
+	v := NewVar(source)
+	expvar.Publish(name, v)
+	return v
+}
+
+// String implements expvar.Var.
+func (v *Var) String() string {
+	s := v.source.Stats()
+
+	b, _ := json.Marshal(map[string]int64{
+		"in_use":          s.InUse,
+		"idle":            s.Idle,
+		"waiting":         s.Waiting,
+		"overflow":        s.Overflow,
+		"peak_total":      s.PeakTotal,
+		"peak_idle":       s.PeakIdle,
+		"created_total":   s.TotalCreated,
+		"destroyed_total": s.TotalDestroyed,
+		"timeouts_total":  s.TimedOut.Count,
+	})
+	return string(b)
+}
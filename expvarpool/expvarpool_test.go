@@ -0,0 +1,58 @@
+package expvarpool_test
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	pool "github.com/posidoni/resource-pool"
+	"github.com/posidoni/resource-pool/expvarpool"
+)
+
+// Expvar names can't be reused, so every test run publishes under a new one.
+var runs atomic.Int64
+
+func TestPublish(t *testing.T) {
+	t.Parallel()
+
+	t.Run(
+		"When pool is published, its stats show up among expvars",
+		func(t *testing.T) {
+			t.Parallel()
+			p := pool.New(
+				2,
+				time.Millisecond,
+				func() (int, error) {
+					return 0, nil
+				},
+				func(r int) {},
+				true,
+			)
+			_, _ = p.Get()
+			r, _ := p.Get()
+			_, _ = p.Get() // times out
+			p.Put(r)
+
+			name := fmt.Sprintf("%s-%d", t.Name(), runs.Add(1))
+			expvarpool.Publish(name, p)
+
+			var stats map[string]int64
+			require.NoError(t, json.Unmarshal([]byte(expvar.Get(name).String()), &stats))
+			require.Equal(t, map[string]int64{
+				"in_use":          1,
+				"idle":            1,
+				"waiting":         0,
+				"overflow":        0,
+				"peak_total":      2,
+				"peak_idle":       1,
+				"created_total":   2,
+				"destroyed_total": 0,
+				"timeouts_total":  1,
+			}, stats)
+		})
+}
@@ -0,0 +1,90 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// Handle holds resource borrowed with Acquire until it is closed.
+type Handle[T any] struct {
+	pool   *Pool[T]
+	value  T
+	once   sync.Once
+	closed atomic.Bool
+}
+
+// Acquire borrows resource like Get does and wraps it into Handle, which puts
+// it back on Close:
+//
+//	h, err := p.Acquire()
+//	if err != nil {
+//		return err
+//	}
+//	defer h.Close()
+//
+// If handle is garbage collected without being closed, resource has leaked:
+// this is logged with logger given with WithLogger.
+func (pool *Pool[T]) Acquire() (*Handle[T], error) {
+	resource, err := pool.Get()
+	if err != nil {
+		return nil, err
+	}
+	h := &Handle[T]{pool: pool, value: resource}
+	if pool.logger != nil {
+		runtime.SetFinalizer(h, func(h *Handle[T]) {
+			if !h.closed.Load() {
+				h.pool.logger.Warn("pool: resource leaked, handle was not closed")
+			}
+		})
+	}
+	return h, nil
+}
+
+// Value returns the borrowed resource. It must not be used after Close.
+func (h *Handle[T]) Value() T {
+	return h.value
+}
+
+// Close puts resource back into the pool like TryReturn does and returns its
+// error. Only the first call puts resource back, later ones do nothing and
+// return nil.
+func (h *Handle[T]) Close() error {
+	var err error
+	h.once.Do(func() {
+		h.closed.Store(true)
+		err = h.pool.TryReturn(h.value)
+	})
+	return err
+}
+
+// AcquireContext borrows resource like GetContext does and returns it along
+// with func putting it back, which suits handlers borrowing with request
+// context:
+//
+//	r, release, err := p.AcquireContext(ctx)
+//	if err != nil {
+//		return err
+//	}
+//	defer release()
+//
+// Only the first call of release puts resource back. Resource pool has no room
+// for is destructed, as with Do.
+func (pool *Pool[T]) AcquireContext(ctx context.Context) (T, func(), error) {
+	resource, err := pool.GetContext(ctx)
+	if err != nil {
+		return resource, func() {}, err
+	}
+
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			if errors.Is(pool.TryReturn(resource), ErrPoolFull) {
+				pool.destroy(resource)
+			}
+		})
+	}
+	return resource, release, nil
+}
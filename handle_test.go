@@ -0,0 +1,170 @@
+package pool_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	pool "github.com/posidoni/resource-pool"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandle(t *testing.T) {
+	t.Parallel()
+
+	t.Run(
+		"When handle is closed several times, object is put back only once",
+		func(t *testing.T) {
+			t.Parallel()
+			puts := int64(0)
+			p := pool.New(
+				1,
+				10*time.Millisecond,
+				func() (*int, error) {
+					return new(int), nil
+				},
+				func(r *int) {},
+				true,
+				pool.WithOnPut(func(r *int) {
+					puts++
+				}),
+			)
+			h, err := p.Acquire()
+			require.NoError(t, err)
+			require.NotNil(t, h.Value())
+			require.Equal(t, int64(1), p.InUse())
+
+			require.NoError(t, h.Close())
+			require.NoError(t, h.Close())
+			require.Equal(t, int64(1), puts)
+			require.Equal(t, int64(1), p.Idle())
+			require.Zero(t, p.InUse())
+		})
+
+	t.Run(
+		"When object can't be acquired, Acquire returns the error",
+		func(t *testing.T) {
+			t.Parallel()
+			p := pool.New(
+				1,
+				10*time.Millisecond,
+				func() (*int, error) {
+					return new(int), nil
+				},
+				func(r *int) {},
+				true,
+			)
+			h, err := p.Acquire()
+			require.NoError(t, err)
+			defer h.Close()
+
+			_, err = p.Acquire()
+			require.ErrorIs(t, err, pool.ErrResourceUnavailable)
+		})
+}
+
+func TestAcquireContext(t *testing.T) {
+	t.Parallel()
+
+	t.Run(
+		"When release is called several times, object is put back only once",
+		func(t *testing.T) {
+			t.Parallel()
+			puts := int64(0)
+			p := pool.New(
+				1,
+				10*time.Millisecond,
+				func() (*int, error) {
+					return new(int), nil
+				},
+				func(r *int) {},
+				true,
+				pool.WithOnPut(func(r *int) {
+					puts++
+				}),
+			)
+			r, release, err := p.AcquireContext(context.Background())
+			require.NoError(t, err)
+			require.NotNil(t, r)
+			require.Equal(t, int64(1), p.InUse())
+
+			release()
+			release()
+			require.Equal(t, int64(1), puts)
+			require.Equal(t, int64(1), p.Idle())
+			require.Zero(t, p.InUse())
+		})
+
+	t.Run(
+		"When context is done, AcquireContext returns its error and release does nothing",
+		func(t *testing.T) {
+			t.Parallel()
+			p := pool.New(
+				1,
+				time.Minute,
+				func() (*int, error) {
+					return new(int), nil
+				},
+				func(r *int) {},
+				true,
+			)
+			_, _ = p.Get()
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+
+			_, release, err := p.AcquireContext(ctx)
+			require.ErrorIs(t, err, context.DeadlineExceeded)
+			release()
+			require.Zero(t, p.Idle())
+		})
+}
+
+func TestHandleLeak(t *testing.T) {
+	t.Run(
+		"When handle is garbage collected without being closed, leak is logged",
+		func(t *testing.T) {
+			var buf syncBuffer
+			p := pool.New(
+				1,
+				10*time.Millisecond,
+				func() (*int, error) {
+					return new(int), nil
+				},
+				func(r *int) {},
+				true,
+				pool.WithLogger[*int](slog.New(slog.NewTextHandler(&buf, nil))),
+			)
+			func() {
+				_, err := p.Acquire()
+				require.NoError(t, err)
+			}()
+
+			require.Eventually(t, func() bool {
+				runtime.GC()
+				return strings.Contains(buf.String(), "resource leaked")
+			}, time.Second, 10*time.Millisecond)
+		})
+}
+
+// Buffer safe to write from finalizer GR while test reads it.
+type syncBuffer struct {
+	m   sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.m.Lock()
+	defer b.m.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.m.Lock()
+	defer b.m.Unlock()
+	return b.buf.String()
+}
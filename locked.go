@@ -0,0 +1,82 @@
+package pool
+
+import (
+	"sync"
+)
+
+// Locked holds resource borrowed exclusively with GetAndLock until it is
+// unlocked.
+type Locked[T any] struct {
+	pool  *Pool[T]
+	value T
+	once  sync.Once
+}
+
+// GetAndLock borrows resource like Get does and locks it: until Unlock, pool
+// refuses to take it back any other way, so it can't be handed out to someone
+// else while caller still uses it:
+//
+//	l, err := p.GetAndLock()
+//	if err != nil {
+//		return err
+//	}
+//	defer l.Unlock()
+//
+// Put and its variants refuse locked resource with ErrLocked and leave it to
+// caller. Resources of non-comparable types can't be tracked by the pool, for
+// them only returning them more than once with Unlock is prevented.
+func (pool *Pool[T]) GetAndLock() (*Locked[T], error) {
+	resource, err := pool.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	if key, ok := trackingKey(resource); ok {
+		pool.m.Lock()
+		if pool.locked == nil {
+			pool.locked = make(map[any]struct{})
+		}
+		pool.locked[key] = struct{}{}
+		pool.m.Unlock()
+	}
+	return &Locked[T]{pool: pool, value: resource}, nil
+}
+
+// Value returns the locked resource. It must not be used after Unlock.
+func (l *Locked[T]) Value() T {
+	return l.value
+}
+
+// Unlock puts resource back into the pool like TryReturn does and returns its
+// error. Resource is returned exactly once: later calls do nothing and return
+// ErrUnlocked.
+func (l *Locked[T]) Unlock() error {
+	err := ErrUnlocked
+	l.once.Do(func() {
+		l.pool.unlock(l.value)
+		err = l.pool.TryReturn(l.value)
+	})
+	return err
+}
+
+// Forgets that resource is locked.
+func (pool *Pool[T]) unlock(resource T) {
+	key, ok := trackingKey(resource)
+	if !ok {
+		return
+	}
+	pool.m.Lock()
+	delete(pool.locked, key)
+	pool.m.Unlock()
+}
+
+// Reports whether resource is locked with GetAndLock. Must be called with
+// pool.m held.
+func (pool *Pool[T]) isLocked(resource T) bool {
+	key, ok := trackingKey(resource)
+	if !ok {
+		return false
+	}
+	_, locked := pool.locked[key]
+	return locked
+}
@@ -0,0 +1,86 @@
+package pool_test
+
+import (
+	"testing"
+	"time"
+
+	pool "github.com/posidoni/resource-pool"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetAndLock(t *testing.T) {
+	t.Parallel()
+
+	t.Run(
+		"When locked object is put back with Put, it is refused and not handed out again",
+		func(t *testing.T) {
+			t.Parallel()
+			dstrCalls := int64(0)
+			p := pool.New(
+				1,
+				10*time.Millisecond,
+				func() (*int, error) {
+					return new(int), nil
+				},
+				func(r *int) { dstrCalls++ },
+				true,
+			)
+			l, err := p.GetAndLock()
+			require.NoError(t, err)
+
+			require.ErrorIs(t, p.TryReturn(l.Value()), pool.ErrLocked)
+			require.Zero(t, p.PutN([]*int{l.Value()}))
+			require.Zero(t, p.Idle())
+			require.Zero(t, dstrCalls, "Locked object is left to its holder")
+
+			_, err = p.Get()
+			require.ErrorIs(t, err, pool.ErrResourceUnavailable, "Object is not handed out twice")
+		})
+
+	t.Run(
+		"When locked object is unlocked several times, it is put back only once",
+		func(t *testing.T) {
+			t.Parallel()
+			p := pool.New(
+				1,
+				10*time.Millisecond,
+				func() (*int, error) {
+					return new(int), nil
+				},
+				func(r *int) {},
+				true,
+			)
+			l, err := p.GetAndLock()
+			require.NoError(t, err)
+			require.Equal(t, int64(1), p.InUse())
+
+			require.NoError(t, l.Unlock())
+			require.ErrorIs(t, l.Unlock(), pool.ErrUnlocked)
+			require.Equal(t, int64(1), p.Idle())
+
+			r, err := p.Get()
+			require.NoError(t, err)
+			require.Same(t, l.Value(), r)
+			require.True(t, p.Put(r), "Unlocked object is put back as usual")
+		})
+
+	t.Run(
+		"When object can't be acquired, GetAndLock returns the error",
+		func(t *testing.T) {
+			t.Parallel()
+			p := pool.New(
+				1,
+				10*time.Millisecond,
+				func() (*int, error) {
+					return new(int), nil
+				},
+				func(r *int) {},
+				true,
+			)
+			_, _ = p.Get()
+
+			l, err := p.GetAndLock()
+			require.ErrorIs(t, err, pool.ErrResourceUnavailable)
+			require.Nil(t, l)
+		})
+}
@@ -0,0 +1,179 @@
+package pool
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// PoolManager keeps a separate pool per key, e.g. per host, created on first
+// use of the key. Every pool is configured with the same options, so WithMax
+// limits each of them rather than all of them together.
+type PoolManager[K comparable, T any] struct {
+	m     sync.Mutex
+	pools map[K]*managedPool[T]
+	// Set by Close, pools are no longer created.
+	closed bool
+
+	factoryFn    func(key K) (T, error)
+	destructorFn func(T)
+	opts         []Option[T]
+	clock        Clock
+}
+
+// Pool of PoolManager along with time it was last used.
+type managedPool[T any] struct {
+	pool     *Pool[T]
+	lastUsed time.Time
+}
+
+// NewPoolManager creates manager whose pools are created like NewWithOptions
+// does, with factoryFn constructing resources for key of the pool.
+func NewPoolManager[K comparable, T any](
+	factoryFn func(key K) (T, error),
+	destructorFn func(T),
+	opts ...Option[T],
+) *PoolManager[K, T] {
+	cfg := defaultConfig[T]()
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return &PoolManager[K, T]{
+		pools:        make(map[K]*managedPool[T]),
+		factoryFn:    factoryFn,
+		destructorFn: destructorFn,
+		opts:         opts,
+		clock:        cfg.clock,
+	}
+}
+
+// Get returns resource from pool of key like Pool.Get does, creating the pool
+// if there is none yet. Once manager is closed, Get fails with ErrPoolClosed.
+func (pm *PoolManager[K, T]) Get(key K) (T, error) {
+	for {
+		p, err := pm.poolFor(key)
+		if err != nil {
+			var defaultValue T
+			return defaultValue, err
+		}
+		resource, err := p.Get()
+		if errors.Is(err, ErrPoolClosed) && pm.reaped(key, p) {
+			// Pool was closed by CloseIdle meanwhile, take a new one.
+			continue
+		}
+		return resource, err
+	}
+}
+
+// Put returns resource to pool of key like Pool.Put does. Resource of key
+// manager has no pool for is not accepted.
+func (pm *PoolManager[K, T]) Put(key K, resource T) bool {
+	pm.m.Lock()
+	mp, ok := pm.pools[key]
+	if ok {
+		mp.lastUsed = pm.clock.Now()
+	}
+	pm.m.Unlock()
+
+	return ok && mp.pool.Put(resource)
+}
+
+// Pool returns pool of key, if there is one, e.g. to inspect its Stats.
+func (pm *PoolManager[K, T]) Pool(key K) (*Pool[T], bool) {
+	pm.m.Lock()
+	defer pm.m.Unlock()
+
+	mp, ok := pm.pools[key]
+	if !ok {
+		return nil, false
+	}
+	return mp.pool, true
+}
+
+// Len returns number of pools manager keeps.
+func (pm *PoolManager[K, T]) Len() int {
+	pm.m.Lock()
+	defer pm.m.Unlock()
+	return len(pm.pools)
+}
+
+// CloseIdle closes and forgets pools that have nothing borrowed and haven't
+// been used for d, so that manager doesn't hold resources for keys that are
+// gone. It is meant to be called periodically and returns number of closed
+// pools along with joined errors of their destructors.
+func (pm *PoolManager[K, T]) CloseIdle(d time.Duration) (int, error) {
+	var idle []*Pool[T]
+
+	pm.m.Lock()
+	if pm.closed {
+		pm.m.Unlock()
+		return 0, nil
+	}
+	now := pm.clock.Now()
+	for key, mp := range pm.pools {
+		if now.Sub(mp.lastUsed) >= d && mp.pool.InUse() == 0 && mp.pool.Stats().Waiting == 0 {
+			delete(pm.pools, key)
+			idle = append(idle, mp.pool)
+		}
+	}
+	pm.m.Unlock()
+
+	var errs []error
+	for _, p := range idle {
+		errs = append(errs, p.Close())
+	}
+	return len(idle), errors.Join(errs...)
+}
+
+// Cleanup is the same as Close.
+func (pm *PoolManager[K, T]) Cleanup() error {
+	return pm.Close()
+}
+
+// Close closes every pool like Pool.Close does. Resources put back afterwards
+// are destructed, Get fails with ErrPoolClosed.
+func (pm *PoolManager[K, T]) Close() error {
+	pm.m.Lock()
+	pm.closed = true
+	pools := make([]*Pool[T], 0, len(pm.pools))
+	for _, mp := range pm.pools {
+		pools = append(pools, mp.pool)
+	}
+	pm.m.Unlock()
+
+	var errs []error
+	for _, p := range pools {
+		errs = append(errs, p.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// Returns pool of key, creating it if needed.
+func (pm *PoolManager[K, T]) poolFor(key K) (*Pool[T], error) {
+	pm.m.Lock()
+	defer pm.m.Unlock()
+
+	if pm.closed {
+		return nil, ErrPoolClosed
+	}
+	mp, ok := pm.pools[key]
+	if !ok {
+		factoryFn := func() (T, error) {
+			return pm.factoryFn(key)
+		}
+		mp = &managedPool[T]{pool: NewWithOptions(factoryFn, pm.destructorFn, pm.opts...)}
+		pm.pools[key] = mp
+	}
+	mp.lastUsed = pm.clock.Now()
+	return mp.pool, nil
+}
+
+// Reports whether p is no longer pool of key, because CloseIdle has closed it.
+func (pm *PoolManager[K, T]) reaped(key K, p *Pool[T]) bool {
+	pm.m.Lock()
+	defer pm.m.Unlock()
+
+	mp, ok := pm.pools[key]
+	return !pm.closed && (!ok || mp.pool != p)
+}
@@ -0,0 +1,96 @@
+package pool_test
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pool "github.com/posidoni/resource-pool"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPoolManager(t *testing.T) {
+	t.Parallel()
+
+	type conn struct{ host string }
+	newManager := func(opts ...pool.Option[*conn]) (*pool.PoolManager[string, *conn], *int64) {
+		dstrCalls := int64(0)
+		return pool.NewPoolManager(
+			func(host string) (*conn, error) { return &conn{host: host}, nil },
+			func(c *conn) { atomic.AddInt64(&dstrCalls, 1) },
+			append([]pool.Option[*conn]{
+				pool.WithMax[*conn](1),
+				pool.WithWait[*conn](10 * time.Millisecond),
+			}, opts...)...,
+		), &dstrCalls
+	}
+
+	t.Run(
+		"When objects of different keys are borrowed, every key gets its own pool",
+		func(t *testing.T) {
+			t.Parallel()
+			pm, _ := newManager()
+			defer pm.Close()
+
+			a, err := pm.Get("a")
+			require.NoError(t, err)
+			require.Equal(t, "a", a.host)
+			b, err := pm.Get("b")
+			require.NoError(t, err)
+			require.Equal(t, "b", b.host)
+			require.Equal(t, 2, pm.Len())
+
+			_, err = pm.Get("a")
+			require.ErrorIs(t, err, pool.ErrResourceUnavailable, "Capacity is per key")
+
+			require.True(t, pm.Put("a", a))
+			a2, err := pm.Get("a")
+			require.NoError(t, err)
+			require.Same(t, a, a2)
+			require.False(t, pm.Put("c", &conn{}), "Unknown key has no pool")
+		})
+
+	t.Run(
+		"When pools are unused for long enough, they are closed unless something is borrowed",
+		func(t *testing.T) {
+			t.Parallel()
+			clock := newFakeClock()
+			pm, dstrCalls := newManager(pool.WithClock[*conn](clock))
+			defer pm.Close()
+
+			a, _ := pm.Get("a")
+			require.True(t, pm.Put("a", a))
+			_, _ = pm.Get("b")
+
+			clock.Advance(time.Minute)
+			closed, err := pm.CloseIdle(time.Minute)
+			require.NoError(t, err)
+			require.Equal(t, 1, closed)
+			require.Equal(t, int64(1), atomic.LoadInt64(dstrCalls))
+			_, ok := pm.Pool("a")
+			require.False(t, ok)
+			_, ok = pm.Pool("b")
+			require.True(t, ok)
+
+			a, err = pm.Get("a")
+			require.NoError(t, err, "Closed pool is recreated on demand")
+			require.Equal(t, "a", a.host)
+		})
+
+	t.Run(
+		"When manager is closed, every pool is closed",
+		func(t *testing.T) {
+			t.Parallel()
+			pm, dstrCalls := newManager()
+			a, _ := pm.Get("a")
+			b, _ := pm.Get("b")
+			require.True(t, pm.Put("a", a))
+
+			require.NoError(t, pm.Cleanup())
+			require.Equal(t, int64(1), atomic.LoadInt64(dstrCalls))
+			_, err := pm.Get("a")
+			require.ErrorIs(t, err, pool.ErrPoolClosed)
+			require.False(t, pm.Put("b", b), "Borrowed object is destructed when put back")
+			require.Equal(t, int64(2), atomic.LoadInt64(dstrCalls))
+		})
+}
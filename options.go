@@ -0,0 +1,487 @@
+package pool
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Option configures optional pool behaviour. Options are passed to New after
+// mandatory arguments or to NewWithOptions.
+type Option[T any] func(*config[T])
+
+// Default time Get waits for resource of a full pool, unless WithWait is given.
+const defaultWaitFor = 30 * time.Second
+
+// Default interval of reaper, unless WithSweepInterval is given.
+const defaultSweepInterval = time.Second
+
+// Interval of keepalive probes if WithKeepalive is given non-positive one.
+const defaultKeepaliveInterval = 30 * time.Second
+
+// Optional pool settings.
+type config[T any] struct {
+	// Pool capacity, (-1) for unlimited pool.
+	max int64
+	// How long Get waits for resource when pool is full.
+	waitFor time.Duration
+	// Whether storage for idle resources is preallocated up to capacity.
+	preallocate bool
+	// Picks idle resource to hand out and evicts idle ones, nil means FIFO.
+	eviction EvictionPolicy[T]
+	// Number of idle resources constructed along with the pool.
+	warmup int
+
+	// Reports whether resource is still usable. Nil means every resource is valid.
+	validateFn func(T) bool
+	// Prepares reused resource for the next user. Nil means no preparation.
+	resetFn func(T) T
+
+	// Weight of resource in pool capacity. Nil means every resource weighs 1.
+	weightFn func(T) int64
+
+	// Max number of idle resources pool retains, (-1) for no limit.
+	maxIdle int64
+
+	// Resources older than this are destructed instead of reused, 0 for no limit.
+	maxLifetime time.Duration
+
+	// Resources handed out this many times are destructed instead of reused,
+	// 0 for no limit.
+	maxUses int
+
+	// Resources idle for longer than this are destructed instead of reused,
+	// 0 for no limit.
+	maxIdleTime time.Duration
+
+	// Constructor that honors cancellation, takes precedence over the plain one.
+	factoryFnCtx func(ctx context.Context) (T, error)
+
+	// Destructor that reports errors, takes precedence over the void one.
+	destructorFnErr func(T) error
+
+	// Max number of constructor calls per construction, 1 means no retries.
+	retryAttempts int
+	// Pause before first retry, doubled after every retry.
+	retryBackoff time.Duration
+	// Reports whether constructor error is worth retrying. Nil means any error is.
+	retryIf func(error) bool
+	// Constructor calls taking longer than this are abandoned, 0 for no limit.
+	createTimeout time.Duration
+	// Max number of constructions in flight, 0 for no limit.
+	maxConcurrentCreates int
+	// Max number of constructor calls per second, 0 for no limit.
+	createRateLimit float64
+
+	// Number of consecutive constructor failures within window that suspends
+	// construction for cooldown, 0 for no circuit breaker.
+	breakerFailures int
+	breakerWindow   time.Duration
+	breakerCooldown time.Duration
+
+	// Whether Put refuses resources that aren't borrowed from the pool.
+	strictPut bool
+
+	// Constructs single-use resource instead of timing out on full pool, nil
+	// means Get fails.
+	overflowFn func() (T, error)
+
+	// Called outside of lock for every resource handed out and accepted back.
+	onGet func(T)
+	onPut func(T)
+	// Called outside of lock whenever Get fails with ErrResourceUnavailable.
+	onTimeout func()
+
+	// Number of idle resources pool keeps constructed in background.
+	minIdle int64
+
+	// How often reaper looks for expired idle resources.
+	sweepInterval time.Duration
+
+	// Probes idle resources every keepaliveInterval, nil means no probing.
+	keepaliveFn       func(T) error
+	keepaliveInterval time.Duration
+
+	// Starts span around every Get, nil means no tracing.
+	tracer trace.Tracer
+
+	// Receives debug diagnostics, nil means no logging.
+	logger *slog.Logger
+
+	// Tells time to the pool.
+	clock Clock
+}
+
+func defaultConfig[T any]() config[T] {
+	return config[T]{
+		max:           -1,
+		waitFor:       defaultWaitFor,
+		retryAttempts: 1,
+		maxIdle:       -1,
+		sweepInterval: defaultSweepInterval,
+		clock:         realClock{},
+	}
+}
+
+// WithMax sets pool capacity, (-1) for unlimited pool, which is the default.
+func WithMax[T any](max int64) Option[T] {
+	return func(c *config[T]) {
+		c.max = max
+	}
+}
+
+// WithWait sets how long Get waits for resource when pool is full before
+// returning ErrResourceUnavailable. Default is 30 seconds. Zero means Get
+// doesn't wait at all, negative waitFor makes Get wait until resource is
+// available or pool is closed. Constructor still running when time runs out
+// makes Get fail with ErrCreateTimeout instead, wrapping constructor's error.
+func WithWait[T any](waitFor time.Duration) Option[T] {
+	return func(c *config[T]) {
+		c.waitFor = waitFor
+	}
+}
+
+// WithPreallocate makes pool preallocate storage for idle resources up to its
+// capacity. With high capacity this may create significant heap pressure.
+// Has no effect on unlimited pool.
+func WithPreallocate[T any](preallocate bool) Option[T] {
+	return func(c *config[T]) {
+		c.preallocate = preallocate
+	}
+}
+
+// WithWarmup makes pool construct n idle resources right away, so that first
+// requests don't pay for construction. Capacity and WithMaxIdle are respected.
+// See Open for handling construction errors.
+func WithWarmup[T any](n int) Option[T] {
+	return func(c *config[T]) {
+		c.warmup = n
+	}
+}
+
+// WithContextFactory makes pool construct resources with factoryFnCtx instead
+// of context-free constructor given to New or NewWithOptions. Resources
+// constructed for GetContext get its context, bounded by the time pool would
+// wait for resource; Get and GetTimeout pass context bounded by their wait
+// time; background construction (warm-up, WithMinIdle) and TryGet pass
+// context.Background().
+func WithContextFactory[T any](factoryFnCtx func(ctx context.Context) (T, error)) Option[T] {
+	return func(c *config[T]) {
+		c.factoryFnCtx = factoryFnCtx
+	}
+}
+
+// WithDestructorErr makes pool destruct resources with destructorFnErr instead
+// of void destructor given to New or NewWithOptions. Errors of resources
+// destructed by Close (or Cleanup) are joined and returned from it; resources
+// destructed in other places, e.g. in Put or by reaper, have no one to report
+// errors to, so they are dropped.
+func WithDestructorErr[T any](destructorFnErr func(T) error) Option[T] {
+	return func(c *config[T]) {
+		c.destructorFnErr = destructorFnErr
+	}
+}
+
+// WithRetry makes pool call constructor up to attempts times before giving up
+// on construction error, pausing for backoff before the first retry and
+// doubling the pause after every retry. Retries of Get stop once the time Get
+// would wait for resource runs out, retries of GetContext also stop when
+// context is done. In both cases the last constructor error is returned.
+func WithRetry[T any](attempts int, backoff time.Duration) Option[T] {
+	return func(c *config[T]) {
+		c.retryAttempts = attempts
+		c.retryBackoff = backoff
+	}
+}
+
+// WithRetryIf limits WithRetry to errors retryable reports true for, others
+// are returned right away.
+func WithRetryIf[T any](retryable func(error) bool) Option[T] {
+	return func(c *config[T]) {
+		c.retryIf = retryable
+	}
+}
+
+// WithCreateTimeout abandons constructor call that takes longer than d, so
+// that a single hanging dial can't wedge its caller: Get fails with
+// ErrCreateTimeout and capacity counted for resource is freed. Context of
+// context-aware constructor expires after d. Constructor that ignores it keeps
+// running in background, and resource it returns too late is destructed.
+// Every call counts separately, retries included. Unlike WithWait, it also
+// bounds constructions for waiting callers. Zero means no limit, which is the
+// default.
+func WithCreateTimeout[T any](d time.Duration) Option[T] {
+	return func(c *config[T]) {
+		c.createTimeout = d
+	}
+}
+
+// WithMaxConcurrentCreates limits number of constructions in flight to n, so
+// that burst of Get calls on cold pool doesn't flood backend with connection
+// attempts. Callers beyond the limit wait for a construction slot within
+// their timeout; if resource is put back meanwhile, one of them takes it
+// instead of constructing another one. Callers who don't wait, i.e. TryGet
+// and Get with zero wait, fail right away if no slot is free. Retries of
+// construction hold its slot. Zero means no limit, which is the default.
+func WithMaxConcurrentCreates[T any](n int) Option[T] {
+	return func(c *config[T]) {
+		c.maxConcurrentCreates = n
+	}
+}
+
+// WithCreateRateLimit limits rate of constructor calls to rps per second, so
+// that pool doesn't overwhelm backend with new connections. Unlike
+// WithMaxConcurrentCreates, it caps constructions over time rather than at
+// once. Limiter is a token bucket holding one second worth of calls (at least
+// one), so bursts up to rps are let through right away. Beyond that Get waits
+// for its turn within its timeout and returns ErrResourceUnavailable once the
+// time runs out. Callers who don't wait, i.e. TryGet and Get with zero wait,
+// fail right away instead.
+// Every constructor call counts, retries and background construction
+// included. Zero means no limit, which is the default.
+func WithCreateRateLimit[T any](rps float64) Option[T] {
+	return func(c *config[T]) {
+		c.createRateLimit = rps
+	}
+}
+
+// WithCircuitBreaker makes pool suspend construction for cooldown once
+// constructor failed failures times in a row within window, so that pool
+// doesn't hammer backend that is down. While construction is suspended, Get
+// hands out idle resources as usual, but fails with ErrCircuitOpen right away
+// instead of constructing one. After cooldown one construction is let
+// through to probe constructor: success resumes construction, failure
+// suspends it for another cooldown. Every constructor call counts, retries
+// included.
+func WithCircuitBreaker[T any](failures int, window, cooldown time.Duration) Option[T] {
+	return func(c *config[T]) {
+		c.breakerFailures = failures
+		c.breakerWindow = window
+		c.breakerCooldown = cooldown
+	}
+}
+
+// WithOnGet sets hook called right after resource is handed out by Get (and
+// its variants), whether resource was idle or freshly constructed. Hook is
+// called outside of pool lock and must be safe for concurrent use.
+func WithOnGet[T any](onGet func(T)) Option[T] {
+	return func(c *config[T]) {
+		c.onGet = onGet
+	}
+}
+
+// WithOnPut sets hook called right after resource is accepted back by Put (or
+// TryReturn). Resources pool refuses or destructs don't trigger it. Hook is
+// called outside of pool lock and must be safe for concurrent use.
+func WithOnPut[T any](onPut func(T)) Option[T] {
+	return func(c *config[T]) {
+		c.onPut = onPut
+	}
+}
+
+// WithOnTimeout sets hook called whenever Get (or its variant) fails with
+// ErrResourceUnavailable, either after waiting or right away when it doesn't
+// wait, e.g. to count saturation of the pool. Hook is called outside of pool
+// lock and must be safe for concurrent use.
+func WithOnTimeout[T any](onTimeout func()) Option[T] {
+	return func(c *config[T]) {
+		c.onTimeout = onTimeout
+	}
+}
+
+// WithMinIdle makes pool keep at least minIdle idle resources: whenever idle
+// resources are taken or destructed, background GR constructs replacements,
+// as long as capacity allows. Resources kept for minimum are not destructed
+// due to WithMaxIdleTime, so that reaper doesn't fight replenishment, but
+// WithMaxLifetime still applies to them.
+func WithMinIdle[T any](minIdle int64) Option[T] {
+	return func(c *config[T]) {
+		c.minIdle = minIdle
+	}
+}
+
+// WithLIFO makes pool hand out most recently returned resource first instead
+// of least recently returned one. This keeps a few resources warm while the
+// rest stay idle and may expire under WithMaxIdleTime. It is the same as
+// WithEvictionPolicy(LIFO[T]()).
+func WithLIFO[T any]() Option[T] {
+	return WithEvictionPolicy(LIFO[T]())
+}
+
+// WithEvictionPolicy makes policy decide which idle resource is handed out
+// next and which idle resources are destructed, on top of WithMaxLifetime,
+// WithMaxIdleTime and the like. Resources policy evicts are destructed even if
+// this leaves fewer than WithMinIdle of them. Built-in policies are FIFO,
+// which is the default, LIFO and LRU.
+func WithEvictionPolicy[T any](policy EvictionPolicy[T]) Option[T] {
+	return func(c *config[T]) {
+		c.eviction = policy
+	}
+}
+
+// WithValidate makes pool check resources with validateFn before handing
+// them out and when they are put back. Idle resources that fail validation are
+// destructed and pool moves on to the next idle one or constructs a fresh one.
+// Returned resources that fail validation are destructed instead of being pooled.
+func WithValidate[T any](validateFn func(T) bool) Option[T] {
+	return func(c *config[T]) {
+		c.validateFn = validateFn
+	}
+}
+
+// WithReset makes pool apply resetFn to reused resource right before handing
+// it out, e.g. to clear buffer, and hand out the result instead. Freshly
+// constructed resources are handed out as is. Resource that can't be reset
+// should be caught with WithValidate, which runs before resetFn.
+func WithReset[T any](resetFn func(T) T) Option[T] {
+	return func(c *config[T]) {
+		c.resetFn = resetFn
+	}
+}
+
+// WithStrictPut makes Put refuse resources that aren't currently borrowed from
+// the pool, so that bugs like putting the same resource back twice or putting
+// resource pool never handed out, e.g. one borrowed from another pool, don't
+// corrupt it: by default such resource is stored as a new one. TryReturn
+// reports refused resource with ErrNotBorrowed; pool doesn't destruct it.
+// Refusal is logged as warning with logger given with WithLogger. Borrowed
+// resources are tracked only for comparable types, so for other types Put is
+// not checked.
+func WithStrictPut[T any]() Option[T] {
+	return func(c *config[T]) {
+		c.strictPut = true
+	}
+}
+
+// WithOverflow makes Get construct resource with overflowFn instead of
+// failing with ErrResourceUnavailable when pool stays full for as long as Get
+// waits, so that rare spikes are absorbed without raising capacity. Overflow
+// resource is used once: when put back, it is destructed rather than stored.
+// Overflow resources don't count towards capacity, see Stats.Overflow.
+// Put recognizes overflow resources only of comparable types, others are
+// taken for resources from outside.
+func WithOverflow[T any](overflowFn func() (T, error)) Option[T] {
+	return func(c *config[T]) {
+		c.overflowFn = overflowFn
+	}
+}
+
+// WithWeight makes pool capacity a budget of total weight of resources rather
+// than their number, for resources that aren't equally heavy: weightFn reports
+// weight of resource, which must stay the same for its whole life. Weight of
+// resource is known only once it is constructed, so pool constructs one only
+// if resource as heavy as the last constructed one fits, otherwise Get waits
+// until it does. Resource turning out heavier than that and not fitting is
+// destructed, and Get waits as well. Resource brought from outside by Put is
+// accepted only if its weight fits. weightFn is called while pool is locked,
+// so it must be quick and must not call pool methods.
+func WithWeight[T any](weightFn func(T) int64) Option[T] {
+	return func(c *config[T]) {
+		c.weightFn = weightFn
+	}
+}
+
+// WithMaxIdle limits number of idle resources pool keeps warm. Resources put
+// back while there are already maxIdle idle ones are destructed, even if
+// total pool capacity allows storing them. It applies to unlimited pool as
+// well, so that burst of borrows doesn't leave it caching resources forever,
+// while borrows stay unlimited. (-1) means no limit, which is the default.
+func WithMaxIdle[T any](maxIdle int64) Option[T] {
+	return func(c *config[T]) {
+		c.maxIdle = maxIdle
+	}
+}
+
+// WithSweepInterval sets how often background reaper destructs idle resources
+// that outlived WithMaxLifetime or WithMaxIdleTime or that EvictionPolicy
+// evicts. Reaper runs only if one of these limits or custom policy is set.
+// Default is one second, which non-positive interval falls back to.
+func WithSweepInterval[T any](interval time.Duration) Option[T] {
+	return func(c *config[T]) {
+		if interval <= 0 {
+			interval = defaultSweepInterval
+		}
+		c.sweepInterval = interval
+	}
+}
+
+// WithKeepalive makes background GR probe every idle resource with keepaliveFn
+// each interval, e.g. to ping database connection before server drops it for
+// being idle. Resource that fails the probe is destructed. While resource is
+// probed, it isn't handed out. Probing doesn't reset idle time that
+// WithMaxIdleTime measures. GR stops when pool is closed. Non-positive
+// interval falls back to 30 seconds.
+func WithKeepalive[T any](interval time.Duration, keepaliveFn func(T) error) Option[T] {
+	return func(c *config[T]) {
+		if interval <= 0 {
+			interval = defaultKeepaliveInterval
+		}
+		c.keepaliveInterval = interval
+		c.keepaliveFn = keepaliveFn
+	}
+}
+
+// WithMaxLifetime makes pool recycle resources older than maxLifetime: expired
+// idle resources are destructed on Get (and a fresh one is constructed instead)
+// as well as expired resources that are put back. Age is counted from the
+// moment resource was constructed by pool or first put into it. Age of
+// resources of non-comparable types can't be tracked while they are borrowed,
+// so it restarts on every Put. Zero means no limit, which is the default.
+func WithMaxLifetime[T any](maxLifetime time.Duration) Option[T] {
+	return func(c *config[T]) {
+		c.maxLifetime = maxLifetime
+	}
+}
+
+// WithMaxUses makes pool recycle resources after they were handed out n
+// times: once resource reaches the limit, it is destructed when put back and
+// Get constructs a replacement on demand. Uses of resources of non-comparable
+// types can't be tracked while they are borrowed, so limit doesn't apply to
+// them. Zero means no limit, which is the default.
+func WithMaxUses[T any](n int) Option[T] {
+	return func(c *config[T]) {
+		c.maxUses = n
+	}
+}
+
+// WithMaxIdleTime makes pool destruct resources that have been sitting idle
+// for longer than maxIdleTime instead of handing them out, so pool doesn't
+// hold connections remote side would have dropped anyway. Zero means no
+// limit, which is the default.
+func WithMaxIdleTime[T any](maxIdleTime time.Duration) Option[T] {
+	return func(c *config[T]) {
+		c.maxIdleTime = maxIdleTime
+	}
+}
+
+// WithTracer makes Get (and its variants taking context) run inside span
+// "pool.Get" started with tracer. Span records how long acquisition took,
+// whether resource was freshly constructed and whether Get timed out. Span of
+// failed Get is marked as error. Context passed to WithContextFactory
+// constructor carries the span.
+func WithTracer[T any](tracer trace.Tracer) Option[T] {
+	return func(c *config[T]) {
+		c.tracer = tracer
+	}
+}
+
+// WithLogger makes pool log at debug level when it constructs or destructs
+// resource, when Get times out and when Put is refused. Without logger pool
+// doesn't log anything.
+func WithLogger[T any](logger *slog.Logger) Option[T] {
+	return func(c *config[T]) {
+		c.logger = logger
+	}
+}
+
+// WithClock makes pool tell time with clock instead of the real one, so that
+// tests can trigger timeouts and expiration without sleeping. Only time of
+// Get waiting, resource ages, construction backoff and reaper sweeps is
+// measured with clock, while deadlines of contexts stay real.
+func WithClock[T any](clock Clock) Option[T] {
+	return func(c *config[T]) {
+		c.clock = clock
+	}
+}
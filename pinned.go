@@ -0,0 +1,101 @@
+package pool
+
+import "context"
+
+// GetPinned returns resource from the pool like Get, preferring the one last
+// put back with PutPinned under the same key, so that a logical worker keeps
+// warm state of its resource, e.g. prepared statements, across borrows. If
+// that resource is gone, e.g. it was handed out to someone else, failed
+// validation or expired, GetPinned falls back to Get. Resources of
+// non-comparable types can't be pinned, for them GetPinned is Get.
+func (pool *Pool[T]) GetPinned(key string) (T, error) {
+	resource, _, err := pool.get(context.Background(), pool.waitTime(), 0, nil, func() (entry[T], bool) {
+		return pool.takePinned(key)
+	})
+	return resource, err
+}
+
+// PutPinned puts resource back into the pool like Put does and pins it to key,
+// so that GetPinned with the same key prefers it. Meanwhile resource is idle
+// as any other and may be handed out by Get, which drops the pin, as does
+// destruction of resource. Pin is moved to resource put back under the same
+// key later.
+func (pool *Pool[T]) PutPinned(key string, resource T) bool {
+	if !pool.Put(resource) {
+		return false
+	}
+	tk, ok := trackingKey(resource)
+	if !ok {
+		return true
+	}
+
+	pool.m.Lock()
+	defer pool.m.Unlock()
+	for _, e := range pool.idle {
+		if k, _ := trackingKey(e.value); k == tk {
+			pool.pin(key, tk)
+			break
+		}
+	}
+	return true
+}
+
+// Takes resource pinned to key out of idle ones and lends it, if it is idle
+// and usable. Expired or invalid one is destructed.
+func (pool *Pool[T]) takePinned(key string) (entry[T], bool) {
+	pool.m.Lock()
+	tk, ok := pool.pins[key]
+	if !ok || pool.closed || pool.draining {
+		pool.m.Unlock()
+		return entry[T]{}, false
+	}
+
+	for i, e := range pool.idle {
+		if k, _ := trackingKey(e.value); k != tk {
+			continue
+		}
+		pool.removeIdle(i)
+		if pool.expired(e) {
+			pool.m.Unlock()
+			pool.discard(e.value)
+			return entry[T]{}, false
+		}
+		pool.m.Unlock()
+		if pool.validateFn != nil && !pool.validateFn(e.value) {
+			pool.discard(e.value)
+			return entry[T]{}, false
+		}
+
+		pool.m.Lock()
+		pool.lend(e)
+		pool.m.Unlock()
+		pool.notifyReplenish()
+		return e, true
+	}
+	pool.m.Unlock()
+	return entry[T]{}, false
+}
+
+// Pins idle resource with tracking key tk to key, replacing previous pins of
+// both. Must be called with pool.m held.
+func (pool *Pool[T]) pin(key string, tk any) {
+	if old, ok := pool.pins[key]; ok {
+		delete(pool.pinnedAs, old)
+	}
+	pool.unpin(tk)
+	if pool.pins == nil {
+		pool.pins = make(map[string]any)
+		pool.pinnedAs = make(map[any]string)
+	}
+	pool.pins[key] = tk
+	pool.pinnedAs[tk] = key
+}
+
+// Drops pin of resource with tracking key tk, if any, once it is no longer
+// idle. Must be called with pool.m held.
+func (pool *Pool[T]) unpin(tk any) {
+	if key, ok := pool.pinnedAs[tk]; ok {
+		delete(pool.pinnedAs, tk)
+		delete(pool.pins, key)
+	}
+}
@@ -0,0 +1,107 @@
+package pool_test
+
+import (
+	"testing"
+	"time"
+
+	pool "github.com/posidoni/resource-pool"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPinned(t *testing.T) {
+	t.Parallel()
+
+	newPool := func(opts ...pool.Option[*int]) *pool.Pool[*int] {
+		return pool.New(
+			3,
+			10*time.Millisecond,
+			func() (*int, error) { return new(int), nil },
+			func(r *int) {},
+			true,
+			opts...,
+		)
+	}
+
+	t.Run(
+		"When object is put back pinned, GetPinned with the same key hands it out regardless of order",
+		func(t *testing.T) {
+			t.Parallel()
+			p := newPool()
+			rs, _ := p.GetN(3)
+			require.True(t, p.Put(rs[0]))
+			require.True(t, p.PutPinned("worker-1", rs[1]))
+			require.True(t, p.PutPinned("worker-2", rs[2]))
+
+			r, err := p.GetPinned("worker-2")
+			require.NoError(t, err)
+			require.Same(t, rs[2], r)
+			r, err = p.GetPinned("worker-1")
+			require.NoError(t, err)
+			require.Same(t, rs[1], r)
+			require.Equal(t, int64(1), p.Idle())
+		})
+
+	t.Run(
+		"When pinned object is gone, GetPinned falls back to any idle object",
+		func(t *testing.T) {
+			t.Parallel()
+			p := newPool()
+			rs, _ := p.GetN(2)
+			require.True(t, p.PutPinned("worker", rs[0]))
+			require.True(t, p.Put(rs[1]))
+
+			r, _ := p.Get()
+			require.Same(t, rs[0], r, "Pinned object is idle as any other")
+
+			pinnedGone, err := p.GetPinned("worker")
+			require.NoError(t, err)
+			require.Same(t, rs[1], pinnedGone)
+		})
+
+	t.Run(
+		"When pinned object fails validation, it is destructed and fresh one is handed out",
+		func(t *testing.T) {
+			t.Parallel()
+			p := newPool(pool.WithValidate(func(r *int) bool { return *r == 0 }))
+			r, _ := p.Get()
+			require.True(t, p.PutPinned("worker", r))
+			*r = 1
+
+			fresh, err := p.GetPinned("worker")
+			require.NoError(t, err)
+			require.NotSame(t, r, fresh)
+			require.Equal(t, int64(1), p.InUse())
+		})
+
+	t.Run(
+		"When pinned object is handed out by Get and put back, it is no longer pinned",
+		func(t *testing.T) {
+			t.Parallel()
+			p := newPool()
+			rs, _ := p.GetN(2)
+			require.True(t, p.PutPinned("worker", rs[0]))
+			require.True(t, p.Put(rs[1]))
+
+			r, _ := p.Get()
+			require.Same(t, rs[0], r)
+			require.True(t, p.Put(r))
+
+			r, err := p.GetPinned("worker")
+			require.NoError(t, err)
+			require.Same(t, rs[1], r, "Pin was dropped once object stopped being idle")
+		})
+
+	t.Run(
+		"When pinned object is destructed, it is no longer pinned",
+		func(t *testing.T) {
+			t.Parallel()
+			p := newPool()
+			r, _ := p.Get()
+			require.True(t, p.PutPinned("worker", r))
+			require.NoError(t, p.ResetIdle())
+
+			fresh, err := p.GetPinned("worker")
+			require.NoError(t, err)
+			require.NotSame(t, r, fresh)
+		})
+}
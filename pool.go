@@ -1,12 +1,39 @@
 package pool
 
 import (
+	"cmp"
+	"context"
 	"errors"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
-var ErrResourceUnavailable = errors.New("timeout while trying to fulfil request, resource unavailable")
+var (
+	ErrResourceUnavailable = errors.New("timeout while trying to fulfil request, resource unavailable")
+	ErrPoolDraining        = errors.New("pool is being drained, resources are no longer handed out")
+	ErrPoolClosed          = errors.New("pool is closed")
+	ErrPoolFull            = errors.New("pool is full, resource was not accepted")
+	ErrResourceDestroyed   = errors.New("resource was destructed by the pool instead of being stored")
+	ErrPanicked            = errors.New("constructor or destructor panicked")
+	ErrCircuitOpen         = errors.New("constructor keeps failing, construction is suspended")
+	ErrNotBorrowed         = errors.New("resource is not borrowed from the pool, it was not accepted")
+	ErrCreateTimeout       = errors.New("timeout while constructing resource")
+	ErrLocked              = errors.New("resource is locked, it must be returned with Unlock, it was not accepted")
+	ErrUnlocked            = errors.New("resource is already unlocked and returned to the pool")
+
+	// Resource put back into closed pool is destructed.
+	errClosedDestroyed = fmt.Errorf("%w: %w", ErrPoolClosed, ErrResourceDestroyed)
+	// Caller who doesn't wait would have to wait to construct resource, so it
+	// is treated like caller of full pool.
+	errWouldWait = errors.New("resource can't be constructed without waiting")
+)
 
 // Represents generic pool of any resources.
 //
@@ -18,165 +45,2201 @@ type Pool[Resource any] struct {
 	m sync.Mutex
 
 	// If there are no resources available, client waits for this long
-	// before getting error. High number may put CPU pressure due to pool
-	// worker actively checking for new resource.
-	// Making memory tradeoff is recommended.
-	waitsForResourceFor time.Duration
+	// before getting error. Holds time.Duration, changed by Reconfigure.
+	waitsForResourceFor atomic.Int64
+
+	// Set whenever resource becomes idle, cleared once Get finds none, so
+	// that Get tries idle resources first only when there may be some.
+	mayHaveIdle atomic.Bool
 
-	// Pool of available (idle) resources.
-	idle map[int64]Resource
+	// Pool of available (idle) resources, ordered from least to most recently
+	// returned. Slice rather than buffered channel: it serves both ends for
+	// LIFO, can be swept and shrunk in place, keeps bookkeeping alongside
+	// resources and needs no capacity, so unlimited pool isn't special.
+	idle []entry[Resource]
 
-	requests chan Request[Resource]
+	// Bookkeeping of resources handed out to users, so it survives the round
+	// trip back to the pool. Only comparable resources can be tracked, see
+	// trackingKey.
+	borrowed map[any][]entry[Resource]
+	// Keys of borrowed resources locked with GetAndLock, which are only taken
+	// back with Unlock. Nil until something is locked.
+	locked map[any]struct{}
+	// Keys of borrowed overflow resources, see WithOverflow, and their total
+	// number. Map is nil until overflow resource is handed out.
+	overflow   map[any]int
+	overflowed int64
+	// Tracking keys of idle resources last put back with PutPinned, by pin,
+	// and pins by tracking key. Pin is dropped once its resource stops being
+	// idle. Nil until something is pinned.
+	pins     map[string]any
+	pinnedAs map[any]string
+	// Description of idle resources for custom EvictionPolicy, reused by
+	// every Get, see popIdle.
+	described []IdleResource[Resource]
 
-	max       int64
+	// Callers waiting for resource of full pool, by priority and then in
+	// arrival order. Returned resources are handed over to them directly
+	// instead of becoming idle.
+	waiters []*Request[Resource]
+	// Recycled requests, see newRequest.
+	requests sync.Pool
+
+	max int64
+	// Number of resources pool is responsible for: idle ones and borrowed ones.
 	objsInUse int64
+	// Total weight of resources pool is responsible for, which is limited by
+	// capacity. Equals objsInUse unless WithWeight is given.
+	weight int64
+	// Weight reserved for resource about to be constructed: weight of the last
+	// constructed one, as resources of one pool tend to weigh alike.
+	expectedWeight int64
+
+	// Constructor, context-free one given to New is wrapped to ignore ctx.
+	factoryFn func(ctx context.Context) (Resource, error)
+	// Destructor, void one given to New is wrapped to report no error.
+	destructorFn func(Resource) error
+	// Suspends construction while constructor keeps failing.
+	breaker breaker
+	// Subscription to pool activity, see Events.
+	events events
+	// Limits rate of constructor calls, nil means no limit.
+	limiter *limiter
+	// Semaphore limiting constructions in flight, nil means no limit.
+	creates chan struct{}
+	// Notifies callers waiting for construction slot about returned resource,
+	// so they can take it instead. Nil when constructions aren't limited:
+	// then callers construct right away and there is no wait to cut short.
+	handoffs chan struct{}
+
+	// Closed once capacity frees up, wakes up PutWait callers. Nil when nobody
+	// waits.
+	roomFreed chan struct{}
+
+	// Notifies replenisher that idle resources were taken or destructed.
+	replenishNotifs chan struct{}
 
-	factoryFn    func() (Resource, error)
-	destructorFn func(Resource)
+	// Cumulative number of constructed and destructed resources.
+	totalCreated   atomic.Int64
+	totalDestroyed atomic.Int64
+	// How long Get calls took to acquire resources.
+	waits waitStats
+	// Highest objsInUse and number of idle resources since start or
+	// ResetPeaks, guarded by pool.m.
+	peakTotal, peakIdle int64
 
-	// Notifies pool maintainer about new available resource.
-	returnNotifs chan struct{}
+	// When pool was created, according to its clock.
+	startedAt time.Time
+
+	// Set by Close, pool neither hands out nor accepts resources.
+	closed bool
+	// Closed on Close, terminates pool's background GRs and unblocks waiters.
+	done chan struct{}
+
+	// Set by Drain, pool no longer hands out resources.
+	draining bool
+	// Closed once nothing is borrowed while pool is draining.
+	drained chan struct{}
+
+	config[Resource]
 }
 
+// Request of Get caller waiting for resource of full pool.
 type Request[T any] struct {
-	e chan error
-	c chan T
+	// Receives the only answer to request. Buffered, so that pool never
+	// blocks answering.
+	c chan handover[T]
+	// Requests of higher priority are served first, see GetPriority.
+	priority int
+	// Constructs resource for request, see GetOrCreate.
+	factoryFn func(ctx context.Context) (T, error)
+}
+
+// Answer to Request: resource handed over to caller or error.
+type handover[T any] struct {
+	value T
+	// Whether resource was constructed for the request.
+	fresh bool
+	err   error
+}
+
+// Resource together with bookkeeping pool keeps about it.
+type entry[T any] struct {
+	value     T
+	createdAt time.Time
+	idleSince time.Time
+	// When resource was handed out last time.
+	lentAt time.Time
+	// Number of times resource was handed out.
+	uses int
 }
 
 // Calls provided destructor for every entity that currently is stored
 // in the pool. Objects which are taken and not returned are not subject
 // to cleanup, because pool no longer owns them.
-func (pool *Pool[T]) Cleanup() {
-	close(pool.requests)
-	for _, r := range pool.idle {
-		pool.destructorFn(r)
+// Cleanup is the same as Close.
+func (pool *Pool[T]) Cleanup() error {
+	return pool.Close()
+}
+
+// ResetIdle destructs every idle resource, leaving borrowed ones alone, so
+// that next Get constructs a fresh one. Unlike Close, pool stays operational.
+// Errors of destructor given with WithDestructorErr are joined and returned.
+func (pool *Pool[T]) ResetIdle() error {
+	pool.m.Lock()
+	idle := pool.takeAllIdle()
+	pool.notifyRoom()
+	pool.notifyDrained()
+	pool.m.Unlock()
+
+	var errs []error
+	for _, resource := range idle {
+		if err := pool.destruct(resource); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	pool.notifyReplenish()
+	return errors.Join(errs...)
+}
+
+// DrainInto takes every idle resource out of the pool without destructing it
+// and returns them, from least to most recently returned, e.g. to hand them
+// over to another pool during migration. Caller owns returned resources: pool
+// no longer counts them, so it may construct fresh ones instead. Borrowed
+// resources are left alone. Unlike Drain, pool stays operational.
+func (pool *Pool[T]) DrainInto() []T {
+	pool.m.Lock()
+	resources := pool.takeAllIdle()
+	pool.notifyRoom()
+	pool.notifyDrained()
+	pool.m.Unlock()
+
+	pool.notifyReplenish()
+	return resources
+}
+
+// Takes every idle resource out of the pool, which no longer counts them, and
+// returns them from least to most recently returned. Must be called with
+// pool.m held.
+func (pool *Pool[T]) takeAllIdle() []T {
+	idle := pool.idle
+	pool.idle = nil
+	if pool.preallocate && pool.max != -1 {
+		pool.idle = make([]entry[T], 0, pool.max)
+	}
+	resources := make([]T, 0, len(idle))
+	for _, e := range idle {
+		pool.disown(e.value)
+		resources = append(resources, e.value)
+	}
+	return resources
+}
+
+// RemoveIdleWhere destructs every idle resource pred reports true for and
+// returns their number, leaving the rest of idle resources and borrowed ones
+// alone. It is a targeted ResetIdle, e.g. for dropping connections opened
+// before schema change. Like fn of ForEachIdle, pred runs while pool is locked.
+func (pool *Pool[T]) RemoveIdleWhere(pred func(T) bool) int {
+	var removed []T
+
+	pool.m.Lock()
+	kept := pool.idle[:0]
+	for _, e := range pool.idle {
+		if pred(e.value) {
+			removed = append(removed, e.value)
+		} else {
+			kept = append(kept, e)
+		}
+	}
+	for i := len(kept); i < len(pool.idle); i++ {
+		pool.idle[i] = entry[T]{}
+	}
+	pool.idle = kept
+	pool.m.Unlock()
+
+	for _, r := range removed {
+		pool.discard(r)
+	}
+	return len(removed)
+}
+
+// ShrinkTo destructs idle resources until at most targetIdle are left and
+// returns number of destructed ones, e.g. to scale down gradually after
+// traffic burst. Least recently returned resources go first, so warm ones
+// stay. Borrowed resources are left alone. Negative targetIdle is the same as
+// zero. Note that WithMinIdle replenishes idle resources below its minimum.
+func (pool *Pool[T]) ShrinkTo(targetIdle int) int {
+	var excess []T
+
+	pool.m.Lock()
+	n := len(pool.idle) - max(targetIdle, 0)
+	for i := 0; i < n; i++ {
+		excess = append(excess, pool.idle[i].value)
+		pool.idle[i] = entry[T]{}
+	}
+	if n > 0 {
+		pool.idle = pool.idle[n:]
+	}
+	pool.m.Unlock()
+
+	for _, r := range excess {
+		pool.discard(r)
+	}
+	return len(excess)
+}
+
+// ForEachIdle calls fn for every idle resource, from least to most recently
+// returned, until fn returns false. Resources stay idle: fn may inspect them
+// but must not hand them out, destruct or put them back. Pool is locked while
+// fn runs, so fn must be quick and must not call pool methods, which would
+// deadlock.
+func (pool *Pool[T]) ForEachIdle(fn func(T) bool) {
+	pool.m.Lock()
+	defer pool.m.Unlock()
+
+	for _, e := range pool.idle {
+		if !fn(e.value) {
+			return
+		}
+	}
+}
+
+// Close transitions pool to closed state: waiting and future Get calls fail
+// with ErrPoolClosed, put back resources are destructed, background GRs are
+// stopped. Then destructor is called for every idle resource. Objects which
+// are taken and not returned are not subject to cleanup, because pool no
+// longer owns them. Closing closed pool does nothing.
+// Errors of destructor given with WithDestructorErr are joined and returned.
+func (pool *Pool[T]) Close() error {
+	idle := pool.shutdown()
+	defer pool.closeEvents()
+
+	var errs []error
+	for _, e := range idle {
+		if err := pool.destruct(e.value); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Number of destructors CleanupTimeout runs at once.
+const cleanupParallelism = 16
+
+// CleanupTimeout closes pool like Close does, but runs destructors of idle
+// resources concurrently, up to 16 at once, which speeds up shutdown of big
+// pools whose destructor does network I/O. It returns once every destructor
+// has finished or ctx is done, whichever comes first, along with number of
+// resources whose destruction hasn't finished by then. These are still
+// destructed in background, but their errors are dropped. Errors of
+// destructor given with WithDestructorErr are joined and returned, along with
+// ctx.Err() if ctx is done first.
+func (pool *Pool[T]) CleanupTimeout(ctx context.Context) (int, error) {
+	idle := pool.shutdown()
+	defer pool.closeEvents()
+
+	queue := make(chan T, len(idle))
+	for _, e := range idle {
+		queue <- e.value
+	}
+	close(queue)
+
+	results := make(chan error, len(idle))
+	for i := 0; i < min(cleanupParallelism, len(idle)); i++ {
+		go func() {
+			for resource := range queue {
+				results <- pool.destruct(resource)
+			}
+		}()
+	}
+
+	var errs []error
+	for finished := 0; finished < len(idle); finished++ {
+		select {
+		case err := <-results:
+			if err != nil {
+				errs = append(errs, err)
+			}
+		case <-ctx.Done():
+			return len(idle) - finished, errors.Join(append(errs, ctx.Err())...)
+		}
+	}
+	return 0, errors.Join(errs...)
+}
+
+// Transitions pool to closed state and takes idle resources away for
+// destruction. Closed pool has none.
+func (pool *Pool[T]) shutdown() []entry[T] {
+	pool.m.Lock()
+	defer pool.m.Unlock()
+
+	if pool.closed {
+		return nil
+	}
+	pool.closed = true
+	close(pool.done)
+	pool.notifyRoom()
+	for len(pool.waiters) > 0 {
+		pool.dequeue().c <- handover[T]{err: ErrPoolClosed}
+	}
+
+	idle := pool.idle
+	pool.idle = nil
+	for _, e := range idle {
+		pool.disown(e.value)
 	}
+	pool.pins, pool.pinnedAs = nil, nil
+	return idle
 }
 
-// New creates new pool and launches one background pool maintainer GR.
+// New creates new pool.
 // If maxSize == -1, pool in unlimited. This means, that pool will try to reuse
 // existing resources, but if there no available, creates them from scratch.
-// User may choose to preallocate map inside pool. With high 'maxSize'
+// User may choose to preallocate storage inside pool. With high 'maxSize'
 // this may create significant heap pressure.
+// Optional behaviour is configured with opts, see Option.
 func New[T any](
 	maxSize int64,
 	waitFor time.Duration,
 	factoryFn func() (T, error),
 	destructorFn func(T),
 	preallocatePool bool,
+	opts ...Option[T],
+) *Pool[T] {
+	return NewWithOptions(
+		factoryFn,
+		destructorFn,
+		append([]Option[T]{
+			WithMax[T](maxSize),
+			WithWait[T](waitFor),
+			WithPreallocate[T](preallocatePool),
+		}, opts...)...,
+	)
+}
+
+// NewUnlimited creates new unlimited pool without preallocated storage, see
+// New. Get waits for resource for 30 seconds, unless WithWait is given.
+func NewUnlimited[T any](
+	factoryFn func() (T, error),
+	destructorFn func(T),
+	opts ...Option[T],
+) *Pool[T] {
+	return New(-1, defaultWaitFor, factoryFn, destructorFn, false, opts...)
+}
+
+// NewBounded creates new pool of capacity max without preallocated storage,
+// see New. Get waits for resource for 30 seconds, unless WithWait is given.
+func NewBounded[T any](
+	max int64,
+	factoryFn func() (T, error),
+	destructorFn func(T),
+	opts ...Option[T],
+) *Pool[T] {
+	return New(max, defaultWaitFor, factoryFn, destructorFn, false, opts...)
+}
+
+// NewWithOptions creates new pool with given constructor and destructor.
+// Everything else is configured with opts. Without options pool is unlimited, see Option for defaults.
+// factoryFn may be nil if WithContextFactory is given, destructorFn may be nil
+// if WithDestructorErr is given.
+// Resources are constructed for WithWarmup best-effort, use Open to find out
+// whether it has failed.
+func NewWithOptions[T any](
+	factoryFn func() (T, error),
+	destructorFn func(T),
+	opts ...Option[T],
 ) *Pool[T] {
+	p, _ := newPool(factoryFn, destructorFn, opts)
+	return p
+}
+
+// NewWithContext creates new pool like NewWithOptions does, which is closed
+// as soon as ctx is done: further Get calls fail with ErrPoolClosed and idle
+// resources are destructed, see Close.
+func NewWithContext[T any](
+	ctx context.Context,
+	factoryFn func() (T, error),
+	destructorFn func(T),
+	opts ...Option[T],
+) *Pool[T] {
+	p := NewWithOptions(factoryFn, destructorFn, opts...)
+	go func() {
+		select {
+		case <-ctx.Done():
+			p.Close()
+		case <-p.done:
+		}
+	}()
+	return p
+}
+
+// Open creates new pool like NewWithOptions does, but reports failure of
+// resource construction for WithWarmup. In such case resources that were
+// already constructed are destructed and pool is closed.
+func Open[T any](
+	factoryFn func() (T, error),
+	destructorFn func(T),
+	opts ...Option[T],
+) (*Pool[T], error) {
+	p, err := newPool(factoryFn, destructorFn, opts)
+	if err != nil {
+		p.Close()
+		return nil, err
+	}
+	return p, nil
+}
+
+// Creates operational pool and warms it up. Returned error is the one that
+// interrupted warm-up, pool is usable regardless.
+func newPool[T any](
+	factoryFn func() (T, error),
+	destructorFn func(T),
+	opts []Option[T],
+) (*Pool[T], error) {
+	cfg := defaultConfig[T]()
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	factoryFnCtx := cfg.factoryFnCtx
+	if factoryFnCtx == nil {
+		factoryFnCtx = func(context.Context) (T, error) {
+			return factoryFn()
+		}
+	}
+	destructorFnErr := cfg.destructorFnErr
+	if destructorFnErr == nil {
+		destructorFnErr = func(resource T) error {
+			destructorFn(resource)
+			return nil
+		}
+	}
+
 	p := &Pool[T]{
-		m:                   sync.Mutex{},
-		waitsForResourceFor: waitFor,
-		requests:            make(chan Request[T]),
-		max:                 maxSize,
-		objsInUse:           0,
-		factoryFn:           factoryFn,
-		destructorFn:        destructorFn,
-		returnNotifs:        make(chan struct{}, 1),
-	}
-
-	if preallocatePool && maxSize != -1 {
-		p.idle = make(map[int64]T, maxSize)
-	} else {
-		p.idle = make(map[int64]T)
+		m:               sync.Mutex{},
+		max:             cfg.max,
+		objsInUse:       0,
+		expectedWeight:  1,
+		factoryFn:       recoverFactory(factoryFnCtx),
+		destructorFn:    recoverDestructor(destructorFnErr),
+		borrowed:        make(map[any][]entry[T]),
+		replenishNotifs: make(chan struct{}, 1),
+		done:            make(chan struct{}),
+		startedAt:       cfg.clock.Now(),
+		config:          cfg,
 	}
+	p.waitsForResourceFor.Store(int64(cfg.waitFor))
 
-	go p.launchPoolMaintainer()
-	return p
+	if cfg.preallocate && cfg.max != -1 {
+		p.idle = make([]entry[T], 0, cfg.max)
+	}
+	if cfg.maxConcurrentCreates > 0 {
+		p.creates = make(chan struct{}, cfg.maxConcurrentCreates)
+		p.handoffs = make(chan struct{})
+	}
+	if cfg.createRateLimit > 0 {
+		p.limiter = newLimiter(cfg.createRateLimit, cfg.clock.Now())
+	}
+
+	err := p.warmUp(cfg.warmup)
+
+	if _, ok := p.eviction.(positional); p.maxLifetime > 0 || p.maxIdleTime > 0 || (p.eviction != nil && !ok) {
+		go p.launchReaper()
+	}
+	if p.minIdle > 0 {
+		go p.launchReplenisher()
+	}
+	if p.keepaliveFn != nil {
+		go p.launchKeepalive()
+	}
+
+	return p, err
 }
 
-// Launches pool maintainer GR. This GR is killed when `pool.Cleanup()` is called.
-// Maintains pool resources, fulfils new requests in case of full pool.
-// Rejects requests for new resources if it's impossible to fulfil them in
-// timely manner.
-func (pool *Pool[T]) launchPoolMaintainer() {
-	for req := range pool.requests {
-		timeout, fulfilled := false, false
-		timeoutChan := time.After(pool.waitsForResourceFor)
-
-		for !timeout && !fulfilled {
-			select {
-			case <-timeoutChan:
-				timeout = true
-				req.e <- ErrResourceUnavailable
-			case <-pool.returnNotifs:
-				pool.m.Lock()
-				for key, r := range pool.idle {
-					delete(pool.idle, key)
-					pool.m.Unlock()
-					req.c <- r
-					fulfilled = true
-				}
+// Wraps constructor, so that its panic is returned as error wrapping
+// ErrPanicked instead of taking down the process.
+func recoverFactory[T any](factoryFn func(context.Context) (T, error)) func(context.Context) (T, error) {
+	return func(ctx context.Context) (resource T, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				var defaultValue T
+				resource, err = defaultValue, fmt.Errorf("%w: constructor: %v", ErrPanicked, r)
+			}
+		}()
+		return factoryFn(ctx)
+	}
+}
+
+// Wraps destructor, so that its panic is returned as error wrapping
+// ErrPanicked instead of taking down the process.
+func recoverDestructor[T any](destructorFn func(T) error) func(T) error {
+	return func(resource T) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("%w: destructor: %v", ErrPanicked, r)
 			}
+		}()
+		return destructorFn(resource)
+	}
+}
+
+// Constructs up to n idle resources, as long as capacity and idle limit allow.
+func (pool *Pool[T]) warmUp(n int) error {
+	built := 0
+	return pool.fill(func() bool {
+		built++
+		return built <= n
+	})
+}
+
+// Constructs idle resources one by one while needed reports they are needed
+// and capacity and idle limit allow. needed is called with pool.m held.
+func (pool *Pool[T]) fill(needed func() bool) error {
+	for {
+		pool.m.Lock()
+		if pool.closed || pool.full() || !needed() ||
+			(pool.maxIdle != -1 && int64(len(pool.idle)) >= pool.maxIdle) {
+			pool.m.Unlock()
+			return nil
 		}
+		w := pool.reserve()
+		pool.m.Unlock()
+
+		_, _, _ = pool.awaitCreateSlot(context.Background(), nil, w, true)
+		resource, err := pool.construct(context.Background(), pool.factoryFn, true)
+		pool.freeCreateSlot()
+		pool.m.Lock()
+		if err != nil {
+			pool.unreserve(w)
+			pool.m.Unlock()
+			return err
+		}
+		if !pool.settle(resource, w) {
+			pool.m.Unlock()
+			pool.destroy(resource)
+			return nil
+		}
+		now := pool.clock.Now()
+		pool.store(entry[T]{value: resource, createdAt: now, idleSince: now})
+		pool.m.Unlock()
 	}
 }
 
-// Returns resource from the pool.
-func (pool *Pool[T]) Get() (T, error) {
+// Launches replenisher GR, which keeps at least WithMinIdle idle resources,
+// as long as capacity allows. It wakes up whenever idle resources are taken
+// or destructed. This GR is killed when `pool.Close()` is called.
+func (pool *Pool[T]) launchReplenisher() {
+	needed := func() bool {
+		return int64(len(pool.idle)) < pool.minIdle
+	}
+
+	for {
+		// Construction errors are not fatal, next wake-up retries.
+		_ = pool.fill(needed)
+
+		select {
+		case <-pool.done:
+			return
+		case <-pool.replenishNotifs:
+		}
+	}
+}
+
+// Wakes replenisher up, if there is one.
+func (pool *Pool[T]) notifyReplenish() {
+	select {
+	case pool.replenishNotifs <- struct{}{}:
+	default:
+	}
+}
+
+// Queues req up for the next resource that is returned or can be
+// constructed, behind waiters of the same or higher priority. Must be called
+// with pool.m held.
+func (pool *Pool[T]) enqueue(req *Request[T]) {
+	i := len(pool.waiters)
+	for i > 0 && pool.waiters[i-1].priority < req.priority {
+		i--
+	}
+	pool.waiters = append(pool.waiters, nil)
+	copy(pool.waiters[i+1:], pool.waiters[i:])
+	pool.waiters[i] = req
+}
+
+// Returns Request ready to be queued up, reusing recycled one if possible, so
+// that waiting doesn't allocate channel every time.
+func (pool *Pool[T]) newRequest(priority int, factoryFn func(ctx context.Context) (T, error)) *Request[T] {
+	req, ok := pool.requests.Get().(*Request[T])
+	if !ok {
+		req = &Request[T]{c: make(chan handover[T], 1)}
+	}
+	req.priority = priority
+	req.factoryFn = factoryFn
+	return req
+}
+
+// Gives req back for reuse. Request must be neither queued up nor about to be
+// answered, and its answer, if any, must have been received, so that next
+// user of req can't get stale answer.
+func (pool *Pool[T]) recycle(req *Request[T]) {
+	req.factoryFn = nil
+	pool.requests.Put(req)
+}
+
+// Removes the first waiter from the queue. Must be called with pool.m held.
+func (pool *Pool[T]) dequeue() *Request[T] {
+	req := pool.waiters[0]
+	pool.waiters[0] = nil
+	pool.waiters = pool.waiters[1:]
+	return req
+}
+
+// Removes req from waiters once its caller gave up waiting. If request has
+// been answered meanwhile, handed over resource is put back. Either way req
+// is recycled once nothing can be sent to it anymore.
+func (pool *Pool[T]) leave(req *Request[T]) {
 	pool.m.Lock()
-	if len(pool.idle) > 0 { // (1) If pool is not empty
-		for key, c := range pool.idle {
-			delete(pool.idle, key)
+	for i, w := range pool.waiters {
+		if w == req {
+			copy(pool.waiters[i:], pool.waiters[i+1:])
+			pool.waiters[len(pool.waiters)-1] = nil
+			pool.waiters = pool.waiters[:len(pool.waiters)-1]
 			pool.m.Unlock()
-			return c, nil
+			pool.recycle(req)
+			return
 		}
 	}
+	pool.m.Unlock()
 
-	// (2) If there are too many existing resources, we have request one from pool
-	if pool.max != -1 && pool.objsInUse >= pool.max {
-		req := Request[T]{
-			c: make(chan T),
-			e: make(chan error),
+	takeBack := func(h handover[T]) {
+		pool.recycle(req)
+		if h.err == nil {
+			pool.release(h.value)
 		}
+	}
+	select {
+	case h := <-req.c:
+		takeBack(h)
+	default:
+		// Resource is still being constructed for the request.
+		go func() {
+			takeBack(<-req.c)
+		}()
+	}
+}
+
+// Hands resource over to the first waiter or, if there are none, stores it
+// into idle ones. While pool is draining, resources always become idle.
+// Must be called with pool.m held.
+func (pool *Pool[T]) store(e entry[T]) {
+	if len(pool.waiters) > 0 && !pool.draining {
+		req := pool.dequeue()
+		pool.lend(e)
+		req.c <- handover[T]{value: e.value}
+		return
+	}
+	pool.idle = append(pool.idle, e)
+	pool.mayHaveIdle.Store(true)
+	pool.notePeaks()
+}
+
+// Constructs resources for waiters, in queue order, as long as capacity
+// allows, e.g. because resource was destructed instead of being returned.
+// Callers of PutWait are woken up to try again. Must be called with pool.m
+// held.
+func (pool *Pool[T]) constructForWaiters() {
+	pool.notifyRoom()
+	for len(pool.waiters) > 0 && !pool.closed && !pool.draining && !pool.full() {
+		w := pool.reserve()
+		go pool.constructFor(pool.dequeue(), w)
+	}
+}
+
+// Constructs resource for req, for which weight w is already counted. If
+// resource turns out too heavy to fit, req is queued up again.
+func (pool *Pool[T]) constructFor(req *Request[T], w int64) {
+	_, _, _ = pool.awaitCreateSlot(context.Background(), nil, w, true)
+	resource, err := pool.construct(context.Background(), req.factoryFn, true)
+	pool.freeCreateSlot()
+
+	pool.m.Lock()
+	if err != nil {
+		pool.unreserve(w)
+		req.c <- handover[T]{err: err}
+		pool.constructForWaiters()
+		pool.m.Unlock()
+		return
+	}
+	if !pool.settle(resource, w) {
+		pool.enqueue(req)
+		pool.m.Unlock()
+		pool.destroy(resource)
+		return
+	}
+	pool.lend(entry[T]{value: resource, createdAt: pool.clock.Now()})
+	req.c <- handover[T]{value: resource, fresh: true}
+	pool.m.Unlock()
+}
 
+// Puts resource, which was handed over to a caller who has left, back into
+// the pool. If pool got closed meanwhile, resource is destructed instead.
+func (pool *Pool[T]) release(resource T) {
+	pool.m.Lock()
+	e, _ := pool.reclaim(resource)
+	if pool.closed {
+		pool.disown(resource)
 		pool.m.Unlock()
-		pool.requests <- req
+		pool.destroy(resource)
+		return
+	}
+	pool.store(e)
+	pool.notifyDrained()
+	pool.m.Unlock()
+	pool.notifyHandoff()
+}
 
+// Launches reaper GR, which periodically destructs expired idle resources, so
+// they don't linger during long idle periods when nobody pulls them.
+// This GR is killed when `pool.Close()` is called.
+func (pool *Pool[T]) launchReaper() {
+	for {
 		select {
-		case c := <-req.c:
-			return c, nil
-		case e := <-req.e:
-			var defaultValue T
-			return defaultValue, e
+		case <-pool.done:
+			return
+		case <-pool.clock.After(pool.sweepInterval):
+			pool.sweep()
 		}
 	}
+}
 
-	// (3) Otherwise, we are free to make resource
-	// Increment objs in use even before creation, because we trust happy path.
-	pool.objsInUse++
+// Launches keepalive GR, which periodically probes idle resources with
+// WithKeepalive function. This GR is killed when `pool.Close()` is called.
+func (pool *Pool[T]) launchKeepalive() {
+	for {
+		select {
+		case <-pool.done:
+			return
+		case <-pool.clock.After(pool.keepaliveInterval):
+			pool.probeIdle()
+		}
+	}
+}
+
+// Probes every resource that is idle at the moment, one at a time, least
+// recently returned first. Resource is taken out of idle ones while probed,
+// then it is stored again or discarded if probe fails.
+func (pool *Pool[T]) probeIdle() {
+	pool.m.Lock()
+	n := len(pool.idle)
 	pool.m.Unlock()
 
-	resource, creationErr := pool.factoryFn()
-	if creationErr != nil {
+	for i := 0; i < n; i++ {
 		pool.m.Lock()
-		pool.objsInUse--
+		if pool.closed || len(pool.idle) == 0 {
+			pool.m.Unlock()
+			return
+		}
+		e := pool.idle[0]
+		pool.idle[0] = entry[T]{}
+		pool.idle = pool.idle[1:]
 		pool.m.Unlock()
-		var defaultValue T
-		return defaultValue, creationErr
-	}
 
-	return resource, nil
+		if err := pool.keepaliveFn(e.value); err != nil {
+			if pool.logger != nil {
+				pool.logger.Debug("pool: keepalive failed", slog.Any("error", err))
+			}
+			pool.discard(e.value)
+			continue
+		}
+
+		pool.m.Lock()
+		if pool.closed {
+			pool.disown(e.value)
+			pool.m.Unlock()
+			pool.destroy(e.value)
+			return
+		}
+		pool.store(e)
+		pool.notifyDrained()
+		pool.m.Unlock()
+		pool.notifyHandoff()
+	}
 }
 
-// Puts resource back into the pool. Returns whether the object was accepted
-// by the pool, which depends on provided pool capacity.
-func (pool *Pool[T]) Put(resource T) bool {
+// Destructs every expired idle resource. Resources are destructed due to idle
+// time only as long as more than WithMinIdle of them remain.
+func (pool *Pool[T]) sweep() {
+	var expired []T
+
 	pool.m.Lock()
+	remaining := int64(len(pool.idle))
+	kept := pool.idle[:0]
+	for _, e := range pool.idle {
+		if pool.outlived(e) || pool.evicts(e) || (pool.idledOut(e) && remaining > pool.minIdle) {
+			expired = append(expired, e.value)
+			remaining--
+		} else {
+			kept = append(kept, e)
+		}
+	}
+	for i := len(kept); i < len(pool.idle); i++ {
+		pool.idle[i] = entry[T]{}
+	}
+	pool.idle = kept
+	pool.m.Unlock()
 
-	if pool.max == -1 || (pool.objsInUse <= pool.max) { // If there is space in the pool
-		pool.idle[pool.objsInUse] = resource
-		pool.objsInUse++
-		pool.m.Unlock()
+	for _, r := range expired {
+		pool.discard(r)
+	}
+}
 
-		// We should notify worker only if the pool is starving
-		if len(pool.requests) > 0 {
-			pool.returnNotifs <- struct{}{}
-		}
-		return true
+// Returns resource from the pool. Idle resources are handed out in a defined
+// order: least recently returned one first, unless WithLIFO or
+// WithEvictionPolicy says otherwise.
+func (pool *Pool[T]) Get() (T, error) {
+	return pool.GetContext(context.Background())
+}
+
+// MustGet returns resource from the pool like Get, but panics if Get fails.
+// It is meant for program initialization, where missing resource is fatal
+// anyway; code serving requests should handle errors of Get instead.
+func (pool *Pool[T]) MustGet() T {
+	resource, err := pool.Get()
+	if err != nil {
+		panic("pool: MustGet: " + err.Error())
+	}
+	return resource
+}
+
+// GetFresh returns resource from the pool like Get and reports whether it was
+// freshly constructed rather than reused, so that caller can initialize new
+// resources only.
+func (pool *Pool[T]) GetFresh() (T, bool, error) {
+	return pool.get(context.Background(), pool.waitTime(), 0, nil, nil)
+}
+
+// ResourceInfo tells what pool knows about resource it hands out, see
+// GetWithInfo.
+type ResourceInfo struct {
+	// Whether resource was constructed for this call.
+	Fresh bool
+	// When resource was constructed by pool or first put into it.
+	CreatedAt time.Time
+	// How long ago it happened, according to pool's clock.
+	Age time.Duration
+	// When resource was put back last time, zero for fresh one.
+	ReturnedAt time.Time
+	// Number of times resource has been handed out, this time included.
+	Uses int
+}
+
+// GetWithInfo returns resource from the pool like Get along with its age and
+// use count, e.g. to recycle resources by hand. Bookkeeping of resources of
+// non-comparable types is lost while they are borrowed, so for them only
+// ResourceInfo.Fresh is reported.
+func (pool *Pool[T]) GetWithInfo() (T, ResourceInfo, error) {
+	resource, fresh, err := pool.get(context.Background(), pool.waitTime(), 0, nil, nil)
+	if err != nil {
+		return resource, ResourceInfo{}, err
+	}
+
+	info := ResourceInfo{Fresh: fresh}
+	key, ok := trackingKey(resource)
+	if !ok {
+		return resource, info, nil
+	}
+	pool.m.Lock()
+	defer pool.m.Unlock()
+	if lent := pool.borrowed[key]; len(lent) > 0 {
+		e := lent[len(lent)-1]
+		info.CreatedAt = e.createdAt
+		info.Age = pool.clock.Now().Sub(e.createdAt)
+		info.ReturnedAt = e.idleSince
+		info.Uses = e.uses
 	}
+	return resource, info, nil
+}
+
+// GetContext returns resource from the pool. It behaves like Get, but gives up
+// waiting and returns ctx.Err() as soon as ctx is done, even if pool's own
+// timeout has not expired yet.
+func (pool *Pool[T]) GetContext(ctx context.Context) (T, error) {
+	resource, _, err := pool.get(ctx, pool.waitTime(), 0, nil, nil)
+	return resource, err
+}
+
+// GetPriority returns resource from the pool like Get, but when pool is full,
+// caller is served before waiting callers of lower priority. Callers of the
+// same priority are served in arrival order; Get and its variants wait with
+// priority 0. Note that under sustained contention callers of lower priority
+// may starve: they get resource only once no caller of higher priority waits.
+func (pool *Pool[T]) GetPriority(p int) (T, error) {
+	resource, _, err := pool.get(context.Background(), pool.waitTime(), p, nil, nil)
+	return resource, err
+}
+
+// GetOrCreate returns resource from the pool like Get, but resource is
+// constructed with create instead of pool's constructor, e.g. when
+// construction parameters vary per call. Idle resource is handed out if there
+// is one, and capacity is respected: when pool is full, caller waits for
+// resource to be returned, or for capacity to free up to call create. Resource
+// constructed with create is owned by the pool afterwards like any other. Call
+// of create is subject to WithRetry, WithCreateRateLimit and
+// WithCircuitBreaker as pool's constructor is.
+func (pool *Pool[T]) GetOrCreate(create func() (T, error)) (T, error) {
+	factoryFn := recoverFactory(func(context.Context) (T, error) {
+		return create()
+	})
+	resource, _, err := pool.get(context.Background(), pool.waitTime(), 0, factoryFn, nil)
+	return resource, err
+}
 
+// GetTimeout returns resource from the pool. It behaves like Get, but waits
+// for resource for d instead of pool-wide timeout. Zero d means that
+// ErrResourceUnavailable is returned right away if nothing is available,
+// negative d means waiting until resource is available or pool is closed.
+func (pool *Pool[T]) GetTimeout(d time.Duration) (T, error) {
+	resource, _, err := pool.get(context.Background(), d, 0, nil, nil)
+	return resource, err
+}
+
+// Gets resource for every Get variant. If prefer is given, resource it takes
+// is handed out before anything else is tried, see takePinned.
+func (pool *Pool[T]) get(
+	ctx context.Context,
+	timeout time.Duration,
+	priority int,
+	factoryFn func(ctx context.Context) (T, error),
+	prefer func() (entry[T], bool),
+) (resource T, fresh bool, err error) {
+	if err = ctx.Err(); err != nil {
+		return resource, fresh, err
+	}
+	start := pool.clock.Now()
+
+	var span trace.Span
+	if pool.tracer != nil {
+		ctx, span = pool.tracer.Start(ctx, "pool.Get")
+	}
+	// How Get ended up, for stats & span. Failures other than timeouts are
+	// not recorded.
+	kind := waitNone
+	defer func() {
+		wait := pool.clock.Now().Sub(start)
+		if kind != waitNone {
+			pool.waits.record(kind, wait)
+		}
+		if kind == waitTimedOut {
+			pool.publish(EventTimedOut)
+			if pool.logger != nil {
+				pool.logger.Debug("pool: Get timed out", slog.Duration("wait", wait), slog.Any("error", err))
+			}
+		}
+		if span != nil {
+			endSpan(span, wait, fresh, kind == waitTimedOut, err)
+		}
+		if pool.onTimeout != nil && errors.Is(err, ErrResourceUnavailable) {
+			pool.onTimeout()
+		}
+	}()
+
+	if prefer != nil {
+		if e, ok := prefer(); ok {
+			kind = waitFast
+			return pool.handOut(e.value, false), false, nil
+		}
+	}
+
+	// Fast path: idle resource is taken before timeout machinery is set up.
+	if pool.mayHaveIdle.Load() {
+		pool.m.Lock()
+		e, ok, err := pool.takeIdle()
+		if !ok && err == nil {
+			pool.m.Unlock()
+		}
+		if err != nil {
+			return resource, fresh, err
+		}
+		if ok {
+			kind = waitFast
+			return pool.handOut(e.value, false), false, nil
+		}
+	}
+
+	// (1) & (3) Idle resource or a fresh one, if capacity allows. Retries of
+	// construction have the same budget as waiting for resource would.
+	// (2) Otherwise caller queues up, unless it doesn't wait at all.
+	budget, cancel := ctx, context.CancelFunc(func() {})
+	if timeout > 0 {
+		budget, cancel = context.WithTimeout(ctx, timeout)
+	}
+	var req *Request[T]
+	if timeout != 0 {
+		req = pool.newRequest(priority, factoryFn)
+	}
+	resource, acq, err := pool.tryGet(budget, req, factoryFn)
+	cancel()
+	if req != nil && (acq != notAcquired || err != nil) {
+		// Request wasn't queued up.
+		pool.recycle(req)
+	}
+	if acq != notAcquired {
+		fresh = acq == acquiredFresh
+		kind = waitFast
+		return pool.handOut(resource, fresh), fresh, nil
+	}
+	if ctx.Err() == nil && (errors.Is(err, ErrCreateTimeout) || errors.Is(err, context.DeadlineExceeded)) {
+		// Pool's own timeout expired. Slow constructor is told apart from
+		// waiting for construction slot or turn, which is lack of capacity.
+		kind = waitTimedOut
+		if !errors.Is(err, ErrCreateTimeout) {
+			err = fmt.Errorf("%w: %w", ErrResourceUnavailable, err)
+		}
+		return resource, fresh, err
+	}
+	if err != nil {
+		return resource, fresh, err
+	}
+	if req == nil {
+		if pool.overflowFn != nil {
+			if resource, err = pool.getOverflow(ctx); err == nil {
+				fresh, kind = true, waitFast
+			}
+			return resource, fresh, err
+		}
+		kind = waitTimedOut
+		return resource, fresh, ErrResourceUnavailable
+	}
+
+	// Negative timeout means waiting forever, nil channel never fires.
+	var timeoutChan <-chan time.Time
+	if timeout > 0 {
+		timeoutChan = pool.clock.After(timeout)
+	}
+
+	select {
+	case h := <-req.c:
+		pool.recycle(req)
+		if h.err != nil {
+			return resource, fresh, h.err
+		}
+		fresh, kind = h.fresh, waitSlow
+		return pool.handOut(h.value, fresh), fresh, nil
+	case <-timeoutChan:
+		pool.leave(req)
+		if pool.overflowFn != nil {
+			if resource, err = pool.getOverflow(ctx); err == nil {
+				fresh, kind = true, waitSlow
+			}
+			return resource, fresh, err
+		}
+		kind = waitTimedOut
+		return resource, fresh, ErrResourceUnavailable
+	case <-ctx.Done():
+		pool.leave(req)
+		kind = waitTimedOut
+		return resource, fresh, ctx.Err()
+	case <-pool.done:
+		pool.leave(req)
+		return resource, fresh, ErrPoolClosed
+	}
+}
+
+// Constructs overflow resource for Get that would time out on full pool, see
+// WithOverflow, and hands it out.
+func (pool *Pool[T]) getOverflow(ctx context.Context) (T, error) {
+	resource, err := recoverFactory(func(context.Context) (T, error) {
+		return pool.overflowFn()
+	})(ctx)
+	if err != nil {
+		return resource, err
+	}
+
+	pool.totalCreated.Add(1)
+	pool.publish(EventCreated)
+	pool.m.Lock()
+	if key, ok := trackingKey(resource); ok {
+		if pool.overflow == nil {
+			pool.overflow = make(map[any]int)
+		}
+		pool.overflow[key]++
+		pool.overflowed++
+	}
+	pool.m.Unlock()
+
+	return pool.handOut(resource, true), nil
+}
+
+// Forgets overflow resource, which is put back or discarded, and reports
+// whether it was one. Must be called with pool.m held.
+func (pool *Pool[T]) unmarkOverflow(resource T) bool {
+	key, ok := trackingKey(resource)
+	if !ok || pool.overflow[key] == 0 {
+		return false
+	}
+	if pool.overflow[key]--; pool.overflow[key] == 0 {
+		delete(pool.overflow, key)
+	}
+	pool.overflowed--
+	return true
+}
+
+// GetN returns n resources from the pool at once. All of them must be
+// acquired within pool-wide timeout, otherwise resources acquired so far are
+// put back and ErrResourceUnavailable is returned, so that callers collecting
+// batches concurrently don't deadlock holding parts of them. If pool waits
+// forever, i.e. its wait is negative, there is no such timeout and this
+// guarantee is given up. Asking for more resources than pool capacity or for
+// negative number of them fails right away.
+func (pool *Pool[T]) GetN(n int) ([]T, error) {
+	if n < 0 {
+		return nil, fmt.Errorf("pool: GetN: number of resources must not be negative, got %d", n)
+	}
+	pool.m.Lock()
+	max := pool.max
+	pool.m.Unlock()
+	if max != -1 && int64(n) > max {
+		return nil, ErrResourceUnavailable
+	}
+
+	wait := pool.waitTime()
+	ctx, cancel := context.Background(), context.CancelFunc(func() {})
+	if wait > 0 {
+		ctx, cancel = context.WithTimeout(ctx, wait)
+	}
+	defer cancel()
+
+	resources := make([]T, 0, n)
+	for len(resources) < n {
+		resource, _, err := pool.get(ctx, wait, 0, nil, nil)
+		if err != nil {
+			for _, r := range resources {
+				pool.Put(r)
+			}
+			if errors.Is(err, context.DeadlineExceeded) && !errors.Is(err, ErrCreateTimeout) {
+				err = ErrResourceUnavailable
+				if pool.onTimeout != nil {
+					pool.onTimeout()
+				}
+			}
+			return nil, err
+		}
+		resources = append(resources, resource)
+	}
+	return resources, nil
+}
+
+// TryGet returns resource from the pool without ever waiting for it to be
+// returned. Reports whether resource was acquired: if there are no idle
+// resources and pool is at capacity, (zero, false, nil) is returned immediately.
+// Error is non-nil only when the constructor fails or pool is draining or
+// closed.
+func (pool *Pool[T]) TryGet() (T, bool, error) {
+	resource, acq, err := pool.tryGet(context.Background(), nil, nil)
+	if acq != notAcquired {
+		resource = pool.handOut(resource, acq == acquiredFresh)
+	}
+	return resource, acq != notAcquired, err
+}
+
+// Prepares resource to be handed out to user: reused one is reset with
+// WithReset function, if there is one, then WithOnGet hook is called.
+func (pool *Pool[T]) handOut(resource T, fresh bool) T {
+	if pool.resetFn != nil && !fresh {
+		reset := pool.resetFn(resource)
+		pool.rekey(resource, reset)
+		resource = reset
+	}
+	pool.publish(EventBorrowed)
+	if pool.onGet != nil {
+		pool.onGet(resource)
+	}
+	return resource
+}
+
+// Moves bookkeeping of borrowed resource to its replacement, so that the
+// replacement is recognized when put back.
+func (pool *Pool[T]) rekey(old, replacement T) {
+	oldKey, ok := trackingKey(old)
+	newKey, _ := trackingKey(replacement)
+	if !ok || oldKey == newKey {
+		return
+	}
+
+	pool.m.Lock()
+	defer pool.m.Unlock()
+	e, counted := pool.reclaim(old)
+	if !counted {
+		return
+	}
+	e.value = replacement
+	if newKey != nil {
+		pool.borrowed[newKey] = append(pool.borrowed[newKey], e)
+	}
+}
+
+// How tryGet obtained resource, if it did.
+type acquisition int
+
+const (
+	notAcquired acquisition = iota
+	acquiredIdle
+	acquiredFresh
+)
+
+// Logs refused Put. Resource WithStrictPut refuses is likely put into the
+// wrong pool or put back twice, which is a bug worth a warning.
+func (pool *Pool[T]) logRefusedPut(err error) {
+	if pool.logger == nil {
+		return
+	}
+	if errors.Is(err, ErrNotBorrowed) {
+		pool.logger.Warn("pool: refused Put of resource not borrowed from this pool", slog.Any("error", err))
+		return
+	}
+	pool.logger.Debug("pool: refused Put", slog.Any("error", err))
+}
+
+// Implements TryGet, ctx bounds retries of construction. Resource is
+// constructed with factoryFn, nil means pool's constructor. If resource can be
+// neither taken nor constructed and req is given, it is queued up. Without
+// req, caller doesn't wait for construction slot or its turn under
+// WithCreateRateLimit either.
+func (pool *Pool[T]) tryGet(
+	ctx context.Context,
+	req *Request[T],
+	factoryFn func(ctx context.Context) (T, error),
+) (T, acquisition, error) {
+	var defaultValue T
+
+	// (1) If pool is not empty, hand out first valid idle resource
+	pool.m.Lock()
+	e, ok, err := pool.takeIdle()
+	if err != nil {
+		return defaultValue, notAcquired, err
+	}
+	if ok {
+		return e.value, acquiredIdle, nil
+	}
+
+	// (2) If there are too many existing resources, caller has to wait
+	if pool.full() {
+		if req != nil {
+			pool.enqueue(req)
+		}
+		pool.m.Unlock()
+		return defaultValue, notAcquired, nil
+	}
+
+	// (3) Otherwise, we are free to make resource
+	// Increment objs in use even before creation, because we trust happy path.
+	w := pool.reserve()
+	pool.m.Unlock()
+
+	e, took, err := pool.awaitCreateSlot(ctx, pool.handoffs, w, req != nil)
+	if took {
+		return e.value, acquiredIdle, nil
+	}
+	if err != nil {
+		pool.m.Lock()
+		pool.unreserve(w)
+		pool.constructForWaiters()
+		pool.m.Unlock()
+		if errors.Is(err, errWouldWait) {
+			return defaultValue, notAcquired, nil
+		}
+		return defaultValue, notAcquired, err
+	}
+
+	resource, creationErr := pool.construct(ctx, factoryFn, req != nil)
+	pool.freeCreateSlot()
+	pool.m.Lock()
+	if creationErr != nil {
+		pool.unreserve(w)
+		pool.constructForWaiters()
+		pool.m.Unlock()
+		if errors.Is(creationErr, errWouldWait) {
+			return defaultValue, notAcquired, nil
+		}
+		return defaultValue, notAcquired, creationErr
+	}
+	if !pool.settle(resource, w) {
+		// Resource is heavier than expected and doesn't fit, so caller has
+		// to wait, as if pool were full in the first place
+		if req != nil {
+			pool.enqueue(req)
+		}
+		pool.m.Unlock()
+		pool.destroy(resource)
+		return defaultValue, notAcquired, nil
+	}
+
+	pool.lend(entry[T]{value: resource, createdAt: pool.clock.Now()})
+	pool.m.Unlock()
+	return resource, acquiredFresh, nil
+}
+
+// Takes first valid idle resource out of the pool and lends it, destructing
+// expired and invalid ones on the way. Must be called with pool.m held. If
+// resource is taken or pool is closed or draining, pool.m is released,
+// otherwise pool has no idle resources and pool.m is still held.
+func (pool *Pool[T]) takeIdle() (entry[T], bool, error) {
+	for {
+		if pool.closed {
+			pool.m.Unlock()
+			return entry[T]{}, false, ErrPoolClosed
+		}
+		if pool.draining {
+			pool.m.Unlock()
+			return entry[T]{}, false, ErrPoolDraining
+		}
+		e, ok := pool.popIdle()
+		if !ok {
+			pool.mayHaveIdle.Store(false)
+			return entry[T]{}, false, nil
+		}
+		if pool.expired(e) {
+			pool.m.Unlock()
+			pool.discard(e.value)
+			pool.m.Lock()
+			continue
+		}
+
+		// Without validation resource is lent right away, saving a round
+		// trip through the lock.
+		if pool.validateFn == nil {
+			pool.lend(e)
+			pool.m.Unlock()
+			pool.notifyReplenish()
+			return e, true, nil
+		}
+		pool.m.Unlock()
+		if pool.validateFn(e.value) {
+			pool.m.Lock()
+			pool.lend(e)
+			pool.m.Unlock()
+			pool.notifyReplenish()
+			return e, true, nil
+		}
+		pool.discard(e.value)
+		pool.m.Lock()
+	}
+}
+
+// Waits for construction slot, if their number is limited by
+// WithMaxConcurrentCreates, on behalf of caller who has counted resource to be
+// constructed with weight w. If resource is put back meanwhile and signal
+// arrives on handoffs, it is taken instead, saving redundant construction: it
+// is lent, count is restored and true is returned. Otherwise slot is taken,
+// unless ctx is done first, and must be freed with freeCreateSlot. Caller who
+// doesn't wait gets errWouldWait right away if no slot is free.
+func (pool *Pool[T]) awaitCreateSlot(ctx context.Context, handoffs <-chan struct{}, w int64, wait bool) (entry[T], bool, error) {
+	if pool.creates != nil && !wait {
+		select {
+		case pool.creates <- struct{}{}:
+			return entry[T]{}, false, nil
+		default:
+			return entry[T]{}, false, errWouldWait
+		}
+	}
+	for pool.creates != nil {
+		select {
+		case pool.creates <- struct{}{}:
+			return entry[T]{}, false, nil
+		case <-ctx.Done():
+			return entry[T]{}, false, ctx.Err()
+		case <-handoffs:
+		}
+
+		pool.m.Lock()
+		e, ok := pool.popIdle()
+		if !ok {
+			pool.m.Unlock()
+			continue
+		}
+		if pool.expired(e) {
+			pool.m.Unlock()
+			pool.discard(e.value)
+			continue
+		}
+		pool.unreserve(w)
+		pool.lend(e)
+		pool.m.Unlock()
+		pool.notifyReplenish()
+		return e, true, nil
+	}
+	return entry[T]{}, false, nil
+}
+
+// Waits until WithCreateRateLimit allows another constructor call. Caller who
+// doesn't wait gets errWouldWait right away if it would have to.
+func (pool *Pool[T]) awaitCreateToken(ctx context.Context, wait bool) error {
+	if pool.limiter == nil {
+		return nil
+	}
+
+	delay := pool.limiter.reserve(pool.clock.Now())
+	if delay <= 0 {
+		return nil
+	}
+	if !wait {
+		pool.limiter.cancel()
+		return errWouldWait
+	}
+	select {
+	case <-pool.clock.After(delay):
+		return nil
+	case <-ctx.Done():
+		pool.limiter.cancel()
+		return ctx.Err()
+	}
+}
+
+func (pool *Pool[T]) freeCreateSlot() {
+	if pool.creates != nil {
+		<-pool.creates
+	}
+}
+
+// Hands returned resource over to a caller waiting for construction slot, if
+// there is one.
+func (pool *Pool[T]) notifyHandoff() {
+	select {
+	case pool.handoffs <- struct{}{}:
+	default:
+	}
+}
+
+// Calls constructor, retrying failures with exponential backoff as configured
+// by WithRetry. ctx is passed to constructor, retries stop early once it is
+// done, last error is returned. factoryFn overrides pool's constructor, if
+// given. Caller who doesn't wait gets errWouldWait instead of waiting for its
+// turn under WithCreateRateLimit.
+func (pool *Pool[T]) construct(ctx context.Context, factoryFn func(ctx context.Context) (T, error), wait bool) (T, error) {
+	if factoryFn == nil {
+		factoryFn = pool.factoryFn
+	}
+	resource, err := pool.callFactory(ctx, factoryFn, wait)
+
+	backoff := pool.retryBackoff
+	for attempt := 1; err != nil && attempt < pool.retryAttempts; attempt++ {
+		if errors.Is(err, errWouldWait) || (pool.retryIf != nil && !pool.retryIf(err)) {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return resource, err
+		case <-pool.clock.After(backoff):
+		}
+
+		backoff *= 2
+		resource, err = pool.callFactory(ctx, factoryFn, wait)
+	}
+
+	if err != nil {
+		if pool.logger != nil {
+			pool.logger.Debug("pool: failed to construct resource", slog.Any("error", err))
+		}
+		return resource, err
+	}
+
+	pool.totalCreated.Add(1)
+	pool.publish(EventCreated)
+	if pool.logger != nil {
+		pool.logger.Debug("pool: constructed resource")
+	}
+	return resource, nil
+}
+
+// Calls factoryFn once, unless circuit breaker given with WithCircuitBreaker
+// has suspended construction. Waits for its turn under WithCreateRateLimit.
+func (pool *Pool[T]) callFactory(ctx context.Context, factoryFn func(ctx context.Context) (T, error), wait bool) (T, error) {
+	if err := pool.awaitCreateToken(ctx, wait); err != nil {
+		var defaultValue T
+		return defaultValue, err
+	}
+	if pool.breakerFailures <= 0 {
+		return pool.runFactory(ctx, factoryFn)
+	}
+
+	if !pool.breaker.allow(pool.clock.Now()) {
+		var defaultValue T
+		return defaultValue, ErrCircuitOpen
+	}
+	resource, err := pool.runFactory(ctx, factoryFn)
+	pool.breaker.record(pool.clock.Now(), err, pool.breakerFailures, pool.breakerWindow, pool.breakerCooldown)
+	return resource, err
+}
+
+// Calls factoryFn once within WithCreateTimeout, if it is given. Constructor
+// that ignores ctx keeps running in its own GR once abandoned, resource it
+// constructs too late is counted as created and destructed right away.
+func (pool *Pool[T]) runFactory(ctx context.Context, factoryFn func(ctx context.Context) (T, error)) (T, error) {
+	if pool.createTimeout <= 0 {
+		resource, err := factoryFn(ctx)
+		return resource, createErr(ctx, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, pool.createTimeout)
+	defer cancel()
+
+	type result struct {
+		resource T
+		err      error
+	}
+	results := make(chan result, 1)
+	go func() {
+		resource, err := factoryFn(ctx)
+		results <- result{resource, err}
+	}()
+
+	select {
+	case r := <-results:
+		return r.resource, createErr(ctx, r.err)
+	case <-ctx.Done():
+		go func() {
+			if r := <-results; r.err == nil {
+				pool.totalCreated.Add(1)
+				pool.publish(EventCreated)
+				pool.destroy(r.resource)
+			}
+		}()
+		var defaultValue T
+		return defaultValue, createErr(ctx, ctx.Err())
+	}
+}
+
+// Wraps constructor failure into ErrCreateTimeout if ctx deadline has passed
+// meanwhile, so that slow constructor is told apart from lack of capacity.
+func createErr(ctx context.Context, err error) error {
+	if err == nil || !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ErrCreateTimeout, err)
+}
+
+// Removes next resource to hand out from idle ones, which WithEvictionPolicy
+// selects: least recently returned one by default, or if policy selects none
+// of them. Must be called with pool.m held.
+func (pool *Pool[T]) popIdle() (entry[T], bool) {
+	n := len(pool.idle)
+	if n == 0 {
+		return entry[T]{}, false
+	}
+
+	i := 0
+	switch policy := pool.eviction.(type) {
+	case nil:
+	case positional:
+		i = policy.position(n)
+	default:
+		idle := pool.described[:0]
+		for _, e := range pool.idle {
+			idle = append(idle, e.describe())
+		}
+		i = policy.Select(idle)
+		clear(idle)
+		pool.described = idle[:0]
+		if i < 0 || i >= n {
+			// Policy is broken, least recently returned one is fine
+			i = 0
+		}
+	}
+	return pool.removeIdle(i), true
+}
+
+// Removes i-th idle resource, keeping order of the rest. Must be called with
+// pool.m held.
+func (pool *Pool[T]) removeIdle(i int) entry[T] {
+	n := len(pool.idle)
+	e := pool.idle[i]
+	switch i {
+	case 0:
+		pool.idle[0] = entry[T]{}
+		pool.idle = pool.idle[1:]
+	default:
+		copy(pool.idle[i:], pool.idle[i+1:])
+		pool.idle[n-1] = entry[T]{}
+		pool.idle = pool.idle[:n-1]
+	}
+	return e
+}
+
+// Reports whether resource, which is taken out of idle ones, has outlived its
+// max lifetime or has been idle for too long. Resources kept warm for
+// WithMinIdle don't expire due to idle time. Must be called with pool.m held.
+func (pool *Pool[T]) expired(e entry[T]) bool {
+	return pool.outlived(e) || pool.evicts(e) || (pool.idledOut(e) && int64(len(pool.idle)) >= pool.minIdle)
+}
+
+// Reports whether WithEvictionPolicy evicts idle resource.
+func (pool *Pool[T]) evicts(e entry[T]) bool {
+	return pool.eviction != nil && pool.eviction.ShouldEvict(e.describe(), pool.clock.Now())
+}
+
+// Reports whether resource was handed out as many times as allowed.
+func (pool *Pool[T]) usedUp(e entry[T]) bool {
+	return pool.maxUses > 0 && e.uses >= pool.maxUses
+}
+
+// Reports whether resource has outlived its max lifetime.
+func (pool *Pool[T]) outlived(e entry[T]) bool {
+	return pool.maxLifetime > 0 && pool.clock.Now().Sub(e.createdAt) >= pool.maxLifetime
+}
+
+// Reports whether resource has been idle for longer than allowed.
+func (pool *Pool[T]) idledOut(e entry[T]) bool {
+	return pool.maxIdleTime > 0 && pool.clock.Now().Sub(e.idleSince) >= pool.maxIdleTime
+}
+
+// Returns key under which resource is tracked while borrowed. Resources of
+// non-comparable types can't be used as map keys and are not tracked.
+func trackingKey[T any](resource T) (any, bool) {
+	key := any(resource)
+	if key == nil || !reflect.TypeOf(key).Comparable() {
+		return nil, false
+	}
+	return key, true
+}
+
+// Remembers bookkeeping of resource handed out to user. Must be called with
+// pool.m held.
+func (pool *Pool[T]) lend(e entry[T]) {
+	e.uses++
+	e.lentAt = pool.clock.Now()
+	if key, ok := trackingKey(e.value); ok {
+		pool.borrowed[key] = append(pool.borrowed[key], e)
+		pool.unpin(key)
+	}
+}
+
+// Restores bookkeeping of resource returned by user. Reports whether resource
+// is already counted in pool.objsInUse, i.e. it was lent by this pool rather
+// than brought from outside. Unknown resources are considered created right
+// now. Resources that can't be tracked are considered lent as long as
+// something is borrowed. Must be called with pool.m held.
+func (pool *Pool[T]) reclaim(resource T) (entry[T], bool) {
+	e := entry[T]{value: resource, createdAt: pool.clock.Now()}
+
+	key, ok := trackingKey(resource)
+	if !ok {
+		return e, pool.objsInUse > int64(len(pool.idle))
+	}
+
+	lent := pool.borrowed[key]
+	if len(lent) == 0 {
+		return e, false
+	}
+
+	e = lent[len(lent)-1]
+	if len(lent) == 1 {
+		delete(pool.borrowed, key)
+	} else {
+		pool.borrowed[key] = lent[:len(lent)-1]
+	}
+	return e, true
+}
+
+// Puts resource back into the pool. Returns whether the object was accepted
+// by the pool, which depends on provided pool capacity.
+// Resource that fails validation, outlived its lifetime, exceeds idle limit,
+// arrives while pool shrinks after SetMax or after pool is closed is
+// destructed by the pool and not accepted.
+// See TryReturn to tell these cases apart.
+func (pool *Pool[T]) Put(resource T) bool {
+	return pool.TryReturn(resource) == nil
+}
+
+// TryReturn puts resource back into the pool like Put does, but reports the
+// outcome as an error: nil if resource was accepted, ErrPoolFull if pool has
+// no room for it (caller still owns resource and is responsible for cleaning
+// it up), ErrResourceDestroyed if pool has destructed it instead of storing.
+// Resource put back into closed pool is destructed as well and error also
+// matches ErrPoolClosed. Under WithStrictPut, resource that isn't borrowed
+// from the pool is refused with ErrNotBorrowed and left to caller. Resource
+// locked with GetAndLock is refused with ErrLocked likewise.
+func (pool *Pool[T]) TryReturn(resource T) error {
+	valid := pool.validateFn == nil || pool.validateFn(resource)
+
+	pool.m.Lock()
+	err := pool.admit(resource, valid)
+	// Waiters may be served with fresh resource instead of destructed one.
+	pool.constructForWaiters()
+	pool.notifyDrained()
+	pool.m.Unlock()
+
+	return pool.putDone(resource, err)
+}
+
+// PutWait puts resource back into the pool like TryReturn does, but if pool
+// has no room for it, waits until capacity frees up, e.g. after SetMax grows
+// it or borrowed resource is destructed, and tries again. Only lack of
+// capacity is waited out: resource beyond WithMaxIdle limit is destructed
+// right away, as any other resource pool refuses. Returns ctx.Err() if ctx is
+// done first, then caller still owns resource.
+func (pool *Pool[T]) PutWait(ctx context.Context, resource T) error {
+	valid := pool.validateFn == nil || pool.validateFn(resource)
+
+	for {
+		pool.m.Lock()
+		err := pool.admit(resource, valid)
+		pool.constructForWaiters()
+		pool.notifyDrained()
+		var room chan struct{}
+		if errors.Is(err, ErrPoolFull) {
+			if pool.roomFreed == nil {
+				pool.roomFreed = make(chan struct{})
+			}
+			room = pool.roomFreed
+		}
+		pool.m.Unlock()
+
+		if room == nil {
+			return pool.putDone(resource, err)
+		}
+		select {
+		case <-room:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Finishes Put of resource once admit has reported err.
+func (pool *Pool[T]) putDone(resource T, err error) error {
+	if errors.Is(err, ErrResourceDestroyed) {
+		pool.destroy(resource)
+	}
+	if err != nil {
+		pool.logRefusedPut(err)
+		return err
+	}
+
+	pool.notifyHandoff()
+	pool.publish(EventReturned)
+	if pool.onPut != nil {
+		pool.onPut(resource)
+	}
+	return nil
+}
+
+// Do borrows resource with Get, calls fn with it and puts it back, so that
+// resource can't leak on error paths. Resource pool refuses back is destructed.
+// Returns error of acquisition or the one returned by fn.
+func (pool *Pool[T]) Do(fn func(T) error) error {
+	return pool.DoContext(context.Background(), fn)
+}
+
+// DoContext is Do that acquires resource with GetContext.
+func (pool *Pool[T]) DoContext(ctx context.Context, fn func(T) error) error {
+	resource, err := pool.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if errors.Is(pool.TryReturn(resource), ErrPoolFull) {
+			pool.destroy(resource)
+		}
+	}()
+	return fn(resource)
+}
+
+// PutN puts resources back into the pool at once and returns how many of
+// them were accepted. Resources pool has no room for or refuses otherwise are
+// destructed, so caller is never left responsible for them, except for ones
+// WithStrictPut refuses and locked ones: these may be owned by someone else,
+// so they are left alone.
+// Every resource is subject to the same checks as in Put.
+func (pool *Pool[T]) PutN(resources []T) int {
+	valid := make([]bool, len(resources))
+	for i, resource := range resources {
+		valid[i] = pool.validateFn == nil || pool.validateFn(resource)
+	}
+
+	var accepted, refused []T
+	pool.m.Lock()
+	for i, resource := range resources {
+		if err := pool.admit(resource, valid[i]); err != nil {
+			if !errors.Is(err, ErrNotBorrowed) && !errors.Is(err, ErrLocked) {
+				refused = append(refused, resource)
+			} else {
+				pool.logRefusedPut(err)
+			}
+			continue
+		}
+		accepted = append(accepted, resource)
+	}
+	pool.constructForWaiters()
+	pool.notifyDrained()
+	pool.m.Unlock()
+
+	for _, resource := range refused {
+		pool.destroy(resource)
+		pool.logRefusedPut(ErrResourceDestroyed)
+	}
+	for range accepted {
+		pool.notifyHandoff()
+		pool.publish(EventReturned)
+	}
+	if pool.onPut != nil {
+		for _, resource := range accepted {
+			pool.onPut(resource)
+		}
+	}
+	return len(accepted)
+}
+
+// Stores returned resource, if pool accepts it. Otherwise
+// reports error matching ErrResourceDestroyed if resource must be destructed
+// (pool no longer counts it), ErrPoolFull if pool has no room for resource it never
+// counted, ErrNotBorrowed if WithStrictPut rejects such resource or ErrLocked
+// if resource is locked with GetAndLock. Must be called under lock.
+func (pool *Pool[T]) admit(resource T, valid bool) error {
+	if pool.isLocked(resource) {
+		return ErrLocked
+	}
+	if pool.unmarkOverflow(resource) {
+		return ErrResourceDestroyed
+	}
+	e, counted := pool.reclaim(resource)
+	e.idleSince = pool.clock.Now()
+
+	if pool.strictPut && !counted {
+		if _, ok := trackingKey(resource); ok {
+			return ErrNotBorrowed
+		}
+	}
+
+	if pool.closed {
+		if counted {
+			pool.disown(resource)
+		}
+		return errClosedDestroyed
+	}
+	if !valid || pool.expired(e) || pool.usedUp(e) || pool.shrinking() ||
+		(pool.maxIdle != -1 && int64(len(pool.idle)) >= pool.maxIdle) {
+		if counted {
+			pool.disown(resource)
+		}
+		return ErrResourceDestroyed
+	}
+
+	if !counted { // Resource is new to the pool and needs a free slot
+		w := pool.weigh(resource)
+		if pool.max != -1 && pool.weight+w > pool.max {
+			return ErrPoolFull
+		}
+		pool.objsInUse++
+		pool.weight += w
+	}
+
+	pool.store(e)
+	return nil
+}
+
+// Drain stops handing out resources and waits until every borrowed resource
+// is put back, then closes the pool. If ctx is done
+// first, idle resources are destructed anyway and ctx.Err() is returned,
+// resources that are still borrowed are abandoned. Errors of closing the pool
+// are returned as well.
+// While Drain waits, Get and TryGet return ErrPoolDraining.
+func (pool *Pool[T]) Drain(ctx context.Context) error {
+	pool.m.Lock()
+	if !pool.draining {
+		pool.draining = true
+		pool.drained = make(chan struct{})
+		for len(pool.waiters) > 0 {
+			pool.dequeue().c <- handover[T]{err: ErrPoolDraining}
+		}
+		pool.notifyDrained()
+	}
+	pool.m.Unlock()
+
+	var err error
+	select {
+	case <-pool.drained:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	return errors.Join(err, pool.Close())
+}
+
+// Wakes up PutWait callers waiting for capacity. Must be called with pool.m
+// held.
+func (pool *Pool[T]) notifyRoom() {
+	if pool.roomFreed != nil {
+		close(pool.roomFreed)
+		pool.roomFreed = nil
+	}
+}
+
+// Closes pool.drained once pool is draining and nothing is borrowed.
+// Must be called with pool.m held.
+func (pool *Pool[T]) notifyDrained() {
+	if !pool.draining || pool.objsInUse != int64(len(pool.idle)) {
+		return
+	}
+	select {
+	case <-pool.drained:
+	default:
+		close(pool.drained)
+	}
+}
+
+// SetMax changes pool capacity, (-1) for unlimited pool. Growing takes effect
+// on the next Get. When shrinking, excess idle resources are destructed right
+// away, while borrowed ones are left alone and destructed by Put as they come
+// back, until pool fits into the new capacity.
+func (pool *Pool[T]) SetMax(n int64) {
+	pool.m.Lock()
+	pool.max = n
+	excess := pool.takeExcess()
+	pool.constructForWaiters()
+	pool.m.Unlock()
+
+	for _, r := range excess {
+		pool.destroy(r)
+	}
+}
+
+// Reconfigure changes pool capacity like SetMax and wait time like WithWait at
+// once, e.g. on configuration reload, without dropping the pool or losing
+// borrowed resources. Get calls already in progress keep the wait time they
+// started with. If resetIdle is set, every idle resource is destructed too, as
+// with ResetIdle, so that fresh ones are constructed under new settings;
+// otherwise only excess ones are. Errors of destructor given with
+// WithDestructorErr are joined and returned.
+func (pool *Pool[T]) Reconfigure(max int64, wait time.Duration, resetIdle bool) error {
+	var excess []T
+
+	pool.m.Lock()
+	pool.max = max
+	pool.waitsForResourceFor.Store(int64(wait))
+	if resetIdle {
+		excess = pool.takeAllIdle()
+	} else {
+		excess = pool.takeExcess()
+	}
+	pool.constructForWaiters()
+	pool.notifyDrained()
+	pool.m.Unlock()
+
+	var errs []error
+	for _, r := range excess {
+		if err := pool.destruct(r); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	pool.notifyReplenish()
+	return errors.Join(errs...)
+}
+
+// Returns how long Get waits for resource of full pool.
+func (pool *Pool[T]) waitTime() time.Duration {
+	return time.Duration(pool.waitsForResourceFor.Load())
+}
+
+// Takes idle resources out of the pool and disowns them while pool is above
+// its capacity, returns them to be destructed. Must be called with pool.m
+// held.
+func (pool *Pool[T]) takeExcess() []T {
+	var excess []T
+	for pool.shrinking() {
+		e, ok := pool.popIdle()
+		if !ok {
+			break
+		}
+		pool.disown(e.value)
+		excess = append(excess, e.value)
+	}
+	return excess
+}
+
+// Reports whether pool owns as many resources as its capacity allows, so that
+// no more can be constructed or accepted. Must be called with pool.m held.
+func (pool *Pool[T]) full() bool {
+	return pool.max != -1 && pool.weight+pool.expectedWeight > pool.max
+}
+
+// Reports whether pool owns more resources than its capacity allows, which
+// happens after SetMax lowered it. Must be called with pool.m held.
+func (pool *Pool[T]) shrinking() bool {
+	return pool.max != -1 && pool.weight > pool.max
+}
+
+// Counts resource about to be constructed, with expected weight until its
+// actual weight is known, and returns weight reserved. Must be called with
+// pool.m held.
+func (pool *Pool[T]) reserve() int64 {
+	w := pool.expectedWeight
+	pool.objsInUse++
+	pool.weight += w
+	pool.notePeaks()
+	return w
+}
+
+// Gives back count of resource reserved with weight w that won't be
+// constructed after all. Must be called with pool.m held.
+func (pool *Pool[T]) unreserve(w int64) {
+	pool.objsInUse--
+	pool.weight -= w
+}
+
+// Replaces reserved weight w with actual weight of constructed resource. If
+// resource is heavier than reserved and doesn't fit into capacity, reservation
+// is given back instead and false is returned: resource must be destructed.
+// Must be called with pool.m held.
+func (pool *Pool[T]) settle(resource T, w int64) bool {
+	actual := pool.weigh(resource)
+	pool.expectedWeight = max(actual, 1)
+	if actual > w && pool.max != -1 && pool.weight-w+actual > pool.max {
+		pool.unreserve(w)
+		return false
+	}
+	pool.weight += actual - w
+	return true
+}
+
+// Stops counting resource pool no longer owns. Must be called with pool.m
+// held.
+func (pool *Pool[T]) disown(resource T) {
+	pool.objsInUse--
+	pool.weight -= pool.weigh(resource)
+	if len(pool.pinnedAs) > 0 {
+		if key, ok := trackingKey(resource); ok {
+			pool.unpin(key)
+		}
+	}
+}
+
+// Returns weight of resource in pool capacity, see WithWeight.
+func (pool *Pool[T]) weigh(resource T) int64 {
+	if pool.weightFn == nil {
+		return 1
+	}
+	return pool.weightFn(resource)
+}
+
+// LeakedSince returns number of resources that have been borrowed for d or
+// longer and not put back yet, which likely means they were leaked. Resources
+// of non-comparable types can't be tracked while they are borrowed and are
+// not reported.
+func (pool *Pool[T]) LeakedSince(d time.Duration) int {
+	pool.m.Lock()
+	defer pool.m.Unlock()
+
+	now := pool.clock.Now()
+	leaked := 0
+	for _, lent := range pool.borrowed {
+		for _, e := range lent {
+			if now.Sub(e.lentAt) >= d {
+				leaked++
+			}
+		}
+	}
+	return leaked
+}
+
+// OutstandingBorrows returns how long each resource currently borrowed from
+// the pool has been held, longest first, e.g. to alert when the oldest borrow
+// points at a stuck GR. Like LeakedSince, it doesn't report resources of
+// non-comparable types.
+func (pool *Pool[T]) OutstandingBorrows() []time.Duration {
+	pool.m.Lock()
+	defer pool.m.Unlock()
+
+	now := pool.clock.Now()
+	var held []time.Duration
+	for _, lent := range pool.borrowed {
+		for _, e := range lent {
+			held = append(held, now.Sub(e.lentAt))
+		}
+	}
+	slices.SortFunc(held, func(a, b time.Duration) int {
+		return cmp.Compare(b, a)
+	})
+	return held
+}
+
+// InUse returns number of resources currently borrowed from the pool.
+func (pool *Pool[T]) InUse() int64 {
+	pool.m.Lock()
+	defer pool.m.Unlock()
+	return pool.objsInUse - int64(len(pool.idle))
+}
+
+// Idle returns number of resources currently stored in the pool.
+func (pool *Pool[T]) Idle() int64 {
+	pool.m.Lock()
+	defer pool.m.Unlock()
+	return int64(len(pool.idle))
+}
+
+// IsFull reports whether Get would have to wait right now: pool is at
+// capacity and has no idle resources. Callers may use it to shed load before
+// calling Get, keeping in mind that answer may be stale by then.
+func (pool *Pool[T]) IsFull() bool {
+	pool.m.Lock()
+	defer pool.m.Unlock()
+	return pool.full() && len(pool.idle) == 0
+}
+
+// Calls destructor, every destruction goes through here.
+func (pool *Pool[T]) destruct(resource T) error {
+	pool.totalDestroyed.Add(1)
+	pool.publish(EventDestroyed)
+	err := pool.destructorFn(resource)
+	if pool.logger != nil {
+		pool.logger.Debug("pool: destructed resource", slog.Any("error", err))
+	}
+	return err
+}
+
+// Destructs resource outside of Close, where there is no one to report
+// destructor error to.
+func (pool *Pool[T]) destroy(resource T) {
+	_ = pool.destruct(resource)
+}
+
+// Destructs resource pool no longer wants to keep and frees its slot.
+func (pool *Pool[T]) discard(resource T) {
+	pool.destroy(resource)
+	pool.m.Lock()
+	pool.disown(resource)
+	pool.constructForWaiters()
+	pool.notifyDrained()
+	pool.m.Unlock()
+	pool.notifyReplenish()
+}
+
+// PutOrDiscard puts resource back like Put if ok is set, otherwise resource is
+// considered dead and is discarded like Discard does. Reports whether resource
+// was accepted back.
+func (pool *Pool[T]) PutOrDiscard(resource T, ok bool) bool {
+	if !ok {
+		pool.Discard(resource)
+		return false
+	}
+	return pool.Put(resource)
+}
+
+// Discard destructs borrowed resource instead of putting it back, e.g. because
+// caller found it broken, and frees its capacity, so that a fresh resource
+// can be constructed instead.
+func (pool *Pool[T]) Discard(resource T) {
+	pool.m.Lock()
+	if key, ok := trackingKey(resource); ok {
+		delete(pool.locked, key)
+	}
+	pool.unmarkOverflow(resource)
+	_, counted := pool.reclaim(resource)
+	if !counted {
+		pool.m.Unlock()
+		pool.destroy(resource)
+		return
+	}
 	pool.m.Unlock()
-	return false
+	pool.discard(resource)
 }
@@ -1,7 +1,13 @@
 package pool_test
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"log"
+	"log/slog"
+	"runtime"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -265,3 +271,3758 @@ func TestPool(t *testing.T) {
 			require.Equal(t, R{5, 5, 5, 5}, r)
 		})
 }
+
+func TestGetContext(t *testing.T) {
+	t.Parallel()
+	type R struct{ a, b, c, d int }
+
+	t.Run(
+		"When context is done before pool timeout, GetContext returns context error without waiting for pool",
+		func(t *testing.T) {
+			t.Parallel()
+			pool := pool.New(
+				1,
+				time.Minute,
+				func() (R, error) {
+					return R{1, 2, 3, 4}, nil
+				},
+				func(r R) {},
+				true,
+			)
+			_, _ = pool.Get()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+
+			start := time.Now()
+			_, err := pool.GetContext(ctx)
+			require.ErrorIs(t, err, context.DeadlineExceeded)
+			require.Less(t, time.Since(start), time.Minute)
+		})
+
+	t.Run(
+		"When context is already cancelled, GetContext neither takes idle objects nor calls CTR",
+		func(t *testing.T) {
+			t.Parallel()
+			ctrCalls := int64(0)
+			pool := pool.New(
+				1,
+				100*time.Millisecond,
+				func() (R, error) {
+					atomic.AddInt64(&ctrCalls, 1)
+					return R{1, 2, 3, 4}, nil
+				},
+				func(r R) {},
+				true,
+			)
+			pool.Put(R{5, 5, 5, 5})
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			_, err := pool.GetContext(ctx)
+			require.ErrorIs(t, err, context.Canceled)
+			require.Equal(t, int64(0), ctrCalls)
+
+			r, err := pool.Get()
+			require.NoError(t, err)
+			require.Equal(t, R{5, 5, 5, 5}, r)
+		})
+}
+
+func TestTryGet(t *testing.T) {
+	t.Parallel()
+	type R struct{ a, b, c, d int }
+
+	t.Run(
+		"When pool is at capacity, TryGet reports failure immediately instead of waiting for timeout",
+		func(t *testing.T) {
+			t.Parallel()
+			ctrCalls := int64(0)
+			pool := pool.New(
+				1,
+				time.Minute,
+				func() (R, error) {
+					atomic.AddInt64(&ctrCalls, 1)
+					return R{1, 2, 3, 4}, nil
+				},
+				func(r R) {},
+				true,
+			)
+			r, ok, err := pool.TryGet()
+			require.NoError(t, err)
+			require.True(t, ok)
+			require.Equal(t, R{1, 2, 3, 4}, r)
+
+			r, ok, err = pool.TryGet()
+			require.NoError(t, err)
+			require.False(t, ok)
+			require.Equal(t, R{}, r)
+			require.Equal(t, int64(1), ctrCalls)
+		})
+
+	t.Run(
+		"When CTR fails, TryGet returns its error and frees the slot",
+		func(t *testing.T) {
+			t.Parallel()
+			errCtr := errors.New("ctr failed")
+			fail := true
+			pool := pool.New(
+				1,
+				100*time.Millisecond,
+				func() (R, error) {
+					if fail {
+						return R{}, errCtr
+					}
+					return R{1, 2, 3, 4}, nil
+				},
+				func(r R) {},
+				true,
+			)
+			_, ok, err := pool.TryGet()
+			require.ErrorIs(t, err, errCtr)
+			require.False(t, ok)
+
+			fail = false
+			r, ok, err := pool.TryGet()
+			require.NoError(t, err)
+			require.True(t, ok)
+			require.Equal(t, R{1, 2, 3, 4}, r)
+		})
+}
+
+func TestGetTimeout(t *testing.T) {
+	t.Parallel()
+	type R struct{ a, b, c, d int }
+
+	newFullPool := func() *pool.Pool[R] {
+		p := pool.New(
+			1,
+			time.Minute,
+			func() (R, error) {
+				return R{1, 2, 3, 4}, nil
+			},
+			func(r R) {},
+			true,
+		)
+		_, _ = p.Get()
+		return p
+	}
+
+	t.Run(
+		"When pool is at capacity, GetTimeout waits for the given duration instead of pool-wide timeout",
+		func(t *testing.T) {
+			t.Parallel()
+			p := newFullPool()
+
+			start := time.Now()
+			_, err := p.GetTimeout(50 * time.Millisecond)
+			require.ErrorIs(t, err, pool.ErrResourceUnavailable)
+			require.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+			require.Less(t, time.Since(start), time.Minute)
+		})
+
+	t.Run(
+		"When zero duration is given and pool is at capacity, GetTimeout fails immediately",
+		func(t *testing.T) {
+			t.Parallel()
+			p := newFullPool()
+
+			_, err := p.GetTimeout(0)
+			require.ErrorIs(t, err, pool.ErrResourceUnavailable)
+		})
+
+	t.Run(
+		"When pool-wide wait is zero, Get never queues up and only hands out available objects",
+		func(t *testing.T) {
+			t.Parallel()
+			p := pool.New(
+				1,
+				0,
+				func() (R, error) {
+					return R{1, 2, 3, 4}, nil
+				},
+				func(r R) {},
+				true,
+			)
+			r, err := p.Get()
+			require.NoError(t, err)
+
+			for i := 0; i < 100; i++ {
+				_, err = p.Get()
+				require.ErrorIs(t, err, pool.ErrResourceUnavailable)
+			}
+			p.Put(r)
+			_, err = p.Get()
+			require.NoError(t, err)
+
+			s := p.Stats()
+			require.Zero(t, s.SlowPath.Count)
+			require.Equal(t, int64(100), s.TimedOut.Count)
+		})
+}
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+	type R struct{ a, b, c, d int }
+
+	t.Run(
+		"When idle object fails validation, pool destructs it and creates a fresh one with CTR",
+		func(t *testing.T) {
+			t.Parallel()
+			ctrCalls := int64(0)
+			dstrCall := int64(0)
+			pool := pool.New(
+				1,
+				100*time.Millisecond,
+				func() (R, error) {
+					atomic.AddInt64(&ctrCalls, 1)
+					return R{1, 2, 3, 4}, nil
+				},
+				func(r R) {
+					atomic.AddInt64(&dstrCall, 1)
+				},
+				true,
+				pool.WithValidate(func(r R) bool {
+					return r != R{0, 0, 0, 0}
+				}),
+			)
+			pool.Put(R{0, 0, 0, 0})
+			r, err := pool.Get()
+
+			require.NoError(t, err)
+			require.Equal(t, R{1, 2, 3, 4}, r)
+			require.Equal(t, int64(1), ctrCalls)
+			require.Equal(t, int64(1), dstrCall)
+		})
+
+	t.Run(
+		"When returned object fails validation, pool destructs it and refuses to store it",
+		func(t *testing.T) {
+			t.Parallel()
+			ctrCalls := int64(0)
+			dstrCall := int64(0)
+			pool := pool.New(
+				1,
+				100*time.Millisecond,
+				func() (*int, error) {
+					atomic.AddInt64(&ctrCalls, 1)
+					return new(int), nil
+				},
+				func(r *int) {
+					atomic.AddInt64(&dstrCall, 1)
+				},
+				true,
+				pool.WithValidate(func(r *int) bool {
+					return *r == 0
+				}),
+			)
+			r, _ := pool.Get()
+			*r = 1 // breaks the object
+
+			require.False(t, pool.Put(r))
+			require.Equal(t, int64(1), dstrCall)
+
+			// Slot of destructed object is free again
+			r, err := pool.Get()
+			require.NoError(t, err)
+			require.Equal(t, 0, *r)
+			require.Equal(t, int64(2), ctrCalls)
+		})
+
+	t.Run(
+		"When half of idle objects are dead, Get skips and destructs them, then creates fresh ones within capacity",
+		func(t *testing.T) {
+			t.Parallel()
+			ctrCalls := int64(0)
+			dstrCall := int64(0)
+			p := pool.New(
+				4,
+				100*time.Millisecond,
+				func() (*int, error) {
+					atomic.AddInt64(&ctrCalls, 1)
+					return new(int), nil
+				},
+				func(r *int) {
+					atomic.AddInt64(&dstrCall, 1)
+				},
+				true,
+				pool.WithValidate(func(r *int) bool {
+					return *r == 0
+				}),
+			)
+			rs, _ := p.GetN(4)
+			require.Equal(t, 4, p.PutN(rs))
+			*rs[0], *rs[2] = 1, 1 // connections drop while idle
+
+			r, err := p.Get()
+			require.NoError(t, err)
+			require.Same(t, rs[1], r)
+			r, err = p.Get()
+			require.NoError(t, err)
+			require.Same(t, rs[3], r)
+			require.Equal(t, int64(2), dstrCall)
+			require.Zero(t, p.Idle())
+
+			fresh, err := p.GetN(2)
+			require.NoError(t, err, "Slots of dead objects are free again")
+			require.Equal(t, 0, *fresh[0])
+			require.Equal(t, int64(6), ctrCalls)
+		})
+}
+
+func TestMaxIdle(t *testing.T) {
+	t.Parallel()
+	type R struct{ a, b, c, d int }
+
+	t.Run(
+		"When there are already maxIdle idle objects, pool destructs returned objects even if capacity allows storing them",
+		func(t *testing.T) {
+			t.Parallel()
+			dstrCall := int64(0)
+			pool := pool.New(
+				5,
+				100*time.Millisecond,
+				func() (R, error) {
+					return R{1, 2, 3, 4}, nil
+				},
+				func(r R) {
+					atomic.AddInt64(&dstrCall, 1)
+				},
+				true,
+				pool.WithMaxIdle[R](2),
+			)
+			require.True(t, pool.Put(R{5, 5, 5, 5}))
+			require.True(t, pool.Put(R{5, 5, 5, 5}))
+			require.False(t, pool.Put(R{5, 5, 5, 5}))
+			require.Equal(t, int64(1), dstrCall)
+
+			pool.Cleanup()
+			require.Equal(t, int64(3), dstrCall)
+		})
+
+	t.Run(
+		"When pool is unlimited, borrows are not capped but idle objects beyond maxIdle are destructed",
+		func(t *testing.T) {
+			t.Parallel()
+			dstrCall := int64(0)
+			p := pool.NewUnlimited(
+				func() (*int, error) {
+					return new(int), nil
+				},
+				func(r *int) {
+					atomic.AddInt64(&dstrCall, 1)
+				},
+				pool.WithMaxIdle[*int](2),
+			)
+			rs, err := p.GetN(10)
+			require.NoError(t, err)
+			require.Equal(t, int64(10), p.InUse())
+
+			require.Equal(t, 2, p.PutN(rs))
+			require.Equal(t, int64(2), p.Idle())
+			require.Equal(t, int64(8), dstrCall)
+			require.Zero(t, p.InUse())
+		})
+}
+
+func TestMaxLifetime(t *testing.T) {
+	t.Parallel()
+
+	t.Run(
+		"When idle object outlived its max lifetime, pool destructs it and creates a fresh one with CTR",
+		func(t *testing.T) {
+			t.Parallel()
+			ctrCalls := int64(0)
+			dstrCall := int64(0)
+			pool := pool.New(
+				-1,
+				100*time.Millisecond,
+				func() (*int, error) {
+					atomic.AddInt64(&ctrCalls, 1)
+					return new(int), nil
+				},
+				func(r *int) {
+					atomic.AddInt64(&dstrCall, 1)
+				},
+				true,
+				pool.WithMaxLifetime[*int](50*time.Millisecond),
+			)
+			first, _ := pool.Get()
+			require.True(t, pool.Put(first))
+
+			// Lifetime is counted from creation, not from the last return
+			time.Sleep(30 * time.Millisecond)
+			r, _ := pool.Get()
+			require.Same(t, first, r)
+			time.Sleep(30 * time.Millisecond)
+			require.False(t, pool.Put(r))
+			require.Equal(t, int64(1), dstrCall)
+
+			r, err := pool.Get()
+			require.NoError(t, err)
+			require.NotSame(t, first, r)
+			require.Equal(t, int64(2), ctrCalls)
+		})
+}
+
+func TestMaxIdleTime(t *testing.T) {
+	t.Parallel()
+	type R struct{ a, b, c, d int }
+
+	t.Run(
+		"When object has been idle longer than max idle time, pool destructs it instead of returning",
+		func(t *testing.T) {
+			t.Parallel()
+			ctrCalls := int64(0)
+			dstrCall := int64(0)
+			pool := pool.New(
+				-1,
+				100*time.Millisecond,
+				func() (R, error) {
+					atomic.AddInt64(&ctrCalls, 1)
+					return R{1, 2, 3, 4}, nil
+				},
+				func(r R) {
+					atomic.AddInt64(&dstrCall, 1)
+				},
+				true,
+				pool.WithMaxIdleTime[R](30*time.Millisecond),
+			)
+			pool.Put(R{5, 5, 5, 5})
+			r, _ := pool.Get()
+			require.Equal(t, R{5, 5, 5, 5}, r)
+
+			pool.Put(R{5, 5, 5, 5})
+			time.Sleep(50 * time.Millisecond)
+			r, _ = pool.Get()
+
+			require.Equal(t, R{1, 2, 3, 4}, r)
+			require.Equal(t, int64(1), ctrCalls)
+			require.Equal(t, int64(1), dstrCall)
+		})
+
+	t.Run(
+		"When nobody pulls objects, reaper still destructs objects idle longer than max idle time",
+		func(t *testing.T) {
+			t.Parallel()
+			dstrCall := int64(0)
+			pool := pool.New(
+				-1,
+				100*time.Millisecond,
+				func() (R, error) {
+					return R{1, 2, 3, 4}, nil
+				},
+				func(r R) {
+					atomic.AddInt64(&dstrCall, 1)
+				},
+				true,
+				pool.WithMaxIdleTime[R](20*time.Millisecond),
+				pool.WithSweepInterval[R](10*time.Millisecond),
+			)
+			defer pool.Cleanup()
+			pool.Put(R{5, 5, 5, 5})
+			pool.Put(R{5, 5, 5, 5})
+
+			require.Eventually(t, func() bool {
+				return atomic.LoadInt64(&dstrCall) == 2
+			}, time.Second, 10*time.Millisecond)
+		})
+
+	t.Run(
+		"When sweep interval is not positive, reaper sweeps every second instead of spinning",
+		func(t *testing.T) {
+			t.Parallel()
+			clock := newFakeClock()
+			dstrCall := int64(0)
+			p := pool.NewWithOptions(
+				func() (*int, error) { return new(int), nil },
+				func(r *int) { atomic.AddInt64(&dstrCall, 1) },
+				pool.WithClock[*int](clock),
+				pool.WithMaxIdleTime[*int](time.Millisecond),
+				pool.WithSweepInterval[*int](0),
+			)
+			defer p.Close()
+			r, _ := p.Get()
+			p.Put(r)
+
+			require.Eventually(t, func() bool { return clock.Pending() == 1 }, time.Second, time.Millisecond)
+			clock.Advance(time.Second - time.Millisecond)
+			require.Equal(t, 1, clock.Pending(), "Reaper waits for the rest of a second")
+			clock.Advance(time.Millisecond)
+			require.Eventually(t, func() bool {
+				return atomic.LoadInt64(&dstrCall) == 1
+			}, time.Second, time.Millisecond)
+		})
+}
+
+func TestInUseAndIdle(t *testing.T) {
+	t.Parallel()
+	type R struct{ a, b, c, d int }
+
+	t.Run(
+		"When objects are taken and returned, pool reports borrowed and idle counts",
+		func(t *testing.T) {
+			t.Parallel()
+			pool := pool.New(
+				5,
+				100*time.Millisecond,
+				func() (R, error) {
+					return R{1, 2, 3, 4}, nil
+				},
+				func(r R) {},
+				true,
+			)
+			pool.Put(R{5, 5, 5, 5})
+			require.Equal(t, int64(0), pool.InUse())
+			require.Equal(t, int64(1), pool.Idle())
+
+			r1, _ := pool.Get()
+			r2, _ := pool.Get()
+			r3, _ := pool.Get()
+			require.Equal(t, int64(3), pool.InUse())
+			require.Equal(t, int64(0), pool.Idle())
+
+			pool.Put(r1)
+			pool.Put(r2)
+			require.Equal(t, int64(1), pool.InUse())
+			require.Equal(t, int64(2), pool.Idle())
+
+			pool.Put(r3)
+			require.Equal(t, int64(0), pool.InUse())
+			require.Equal(t, int64(3), pool.Idle())
+		})
+}
+
+func TestSetMax(t *testing.T) {
+	t.Parallel()
+	type R struct{ a, b, c, d int }
+
+	t.Run(
+		"When max is lowered, pool destructs excess idle objects and returned objects until it fits",
+		func(t *testing.T) {
+			t.Parallel()
+			dstrCall := int64(0)
+			pool := pool.New(
+				4,
+				100*time.Millisecond,
+				func() (R, error) {
+					return R{1, 2, 3, 4}, nil
+				},
+				func(r R) {
+					atomic.AddInt64(&dstrCall, 1)
+				},
+				true,
+			)
+			r1, _ := pool.Get()
+			r2, _ := pool.Get()
+			pool.Put(R{5, 5, 5, 5})
+			pool.Put(R{5, 5, 5, 5})
+
+			pool.SetMax(1)
+			require.Equal(t, int64(2), dstrCall)
+			require.Equal(t, int64(2), pool.InUse())
+
+			require.False(t, pool.Put(r1))
+			require.Equal(t, int64(3), dstrCall)
+			require.True(t, pool.Put(r2))
+			require.Equal(t, int64(1), pool.Idle())
+		})
+
+	t.Run(
+		"When max is raised, pool creates more objects right away",
+		func(t *testing.T) {
+			t.Parallel()
+			ctrCalls := int64(0)
+			pool := pool.New(
+				1,
+				100*time.Millisecond,
+				func() (R, error) {
+					atomic.AddInt64(&ctrCalls, 1)
+					return R{1, 2, 3, 4}, nil
+				},
+				func(r R) {},
+				true,
+			)
+			_, _ = pool.Get()
+			_, ok, _ := pool.TryGet()
+			require.False(t, ok)
+
+			pool.SetMax(-1)
+			_, ok, _ = pool.TryGet()
+			require.True(t, ok)
+			require.Equal(t, int64(2), ctrCalls)
+		})
+}
+
+func TestReconfigure(t *testing.T) {
+	t.Parallel()
+
+	t.Run(
+		"When pool is reconfigured with idle reset, idle objects are recycled and borrowed ones are kept",
+		func(t *testing.T) {
+			t.Parallel()
+			dstrCalls := int64(0)
+			p := pool.New(
+				2,
+				time.Minute,
+				func() (*int, error) { return new(int), nil },
+				func(r *int) { atomic.AddInt64(&dstrCalls, 1) },
+				true,
+			)
+			rs, _ := p.GetN(2)
+			p.Put(rs[0])
+
+			require.NoError(t, p.Reconfigure(3, 0, true))
+			require.Equal(t, int64(1), dstrCalls)
+			require.Zero(t, p.Idle())
+			require.Equal(t, int64(1), p.InUse())
+			require.Equal(t, int64(3), p.Config().Max)
+			require.Zero(t, p.Config().Wait)
+
+			_, err := p.GetN(2)
+			require.NoError(t, err)
+			start := time.Now()
+			_, err = p.Get()
+			require.ErrorIs(t, err, pool.ErrResourceUnavailable, "New wait time applies")
+			require.Less(t, time.Since(start), time.Minute)
+			require.True(t, p.Put(rs[1]), "Borrowed object is still accepted back")
+		})
+
+	t.Run(
+		"When pool is reconfigured without idle reset, only excess idle objects are destructed",
+		func(t *testing.T) {
+			t.Parallel()
+			dstrCalls := int64(0)
+			p := pool.New(
+				3,
+				time.Minute,
+				func() (*int, error) { return new(int), nil },
+				func(r *int) { atomic.AddInt64(&dstrCalls, 1) },
+				true,
+			)
+			rs, _ := p.GetN(3)
+			p.PutN(rs)
+
+			require.NoError(t, p.Reconfigure(1, time.Second, false))
+			require.Equal(t, int64(2), dstrCalls)
+			require.Equal(t, int64(1), p.Idle())
+		})
+
+	t.Run(
+		"When pool is reconfigured under load, Get and Put keep working and capacity holds",
+		func(t *testing.T) {
+			t.Parallel()
+			p := pool.New(
+				4,
+				time.Second,
+				func() (*int, error) { return new(int), nil },
+				func(r *int) {},
+				false,
+			)
+
+			done := make(chan struct{})
+			for i := 0; i < 8; i++ {
+				go func() {
+					defer func() { done <- struct{}{} }()
+					for j := 0; j < 100; j++ {
+						r, err := p.Get()
+						if err != nil {
+							continue
+						}
+						p.Put(r)
+					}
+				}()
+			}
+			for i := 0; i < 20; i++ {
+				_ = p.Reconfigure(int64(2+i%3), time.Second, i%2 == 0)
+			}
+			for i := 0; i < 8; i++ {
+				<-done
+			}
+
+			require.Zero(t, p.InUse())
+			require.LessOrEqual(t, p.Idle(), p.Config().Max)
+			s := p.Stats()
+			require.Equal(t, s.TotalCreated-s.TotalDestroyed, s.Idle)
+		})
+}
+
+func TestDrain(t *testing.T) {
+	t.Parallel()
+	type R struct{ a, b, c, d int }
+
+	t.Run(
+		"When objects are borrowed, Drain waits for them to be returned and destructs all of them",
+		func(t *testing.T) {
+			t.Parallel()
+			dstrCall := int64(0)
+			p := pool.New(
+				2,
+				100*time.Millisecond,
+				func() (*R, error) {
+					return &R{1, 2, 3, 4}, nil
+				},
+				func(r *R) {
+					atomic.AddInt64(&dstrCall, 1)
+				},
+				true,
+			)
+			r1, _ := p.Get()
+			r2, _ := p.Get()
+			p.Put(r1)
+
+			go func() {
+				time.Sleep(20 * time.Millisecond)
+				p.Put(r2)
+			}()
+
+			require.NoError(t, p.Drain(context.Background()))
+			require.Equal(t, int64(2), dstrCall)
+
+			_, err := p.Get()
+			require.ErrorIs(t, err, pool.ErrPoolClosed)
+		})
+
+	t.Run(
+		"When context expires before objects are returned, Drain returns error, but still destructs idle objects",
+		func(t *testing.T) {
+			t.Parallel()
+			dstrCall := int64(0)
+			p := pool.New(
+				2,
+				100*time.Millisecond,
+				func() (*R, error) {
+					return &R{1, 2, 3, 4}, nil
+				},
+				func(r *R) {
+					atomic.AddInt64(&dstrCall, 1)
+				},
+				true,
+			)
+			r1, _ := p.Get()
+			_, _ = p.Get()
+			p.Put(r1)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+
+			require.ErrorIs(t, p.Drain(ctx), context.DeadlineExceeded)
+			require.Equal(t, int64(1), dstrCall)
+		})
+}
+
+func TestClose(t *testing.T) {
+	t.Parallel()
+	type R struct{ a, b, c, d int }
+
+	t.Run(
+		"When pool is closed, waiting and future Get calls fail with ErrPoolClosed",
+		func(t *testing.T) {
+			t.Parallel()
+			p := pool.New(
+				1,
+				time.Minute,
+				func() (R, error) {
+					return R{1, 2, 3, 4}, nil
+				},
+				func(r R) {},
+				true,
+			)
+			_, _ = p.Get()
+
+			errs := make(chan error)
+			for i := 0; i < 3; i++ {
+				go func() {
+					_, err := p.Get()
+					errs <- err
+				}()
+			}
+
+			time.Sleep(20 * time.Millisecond)
+			p.Close()
+
+			for i := 0; i < 3; i++ {
+				require.ErrorIs(t, <-errs, pool.ErrPoolClosed)
+			}
+			_, err := p.Get()
+			require.ErrorIs(t, err, pool.ErrPoolClosed)
+		})
+
+	t.Run(
+		"When object is put back into closed pool, pool destructs it",
+		func(t *testing.T) {
+			t.Parallel()
+			dstrCall := int64(0)
+			p := pool.New(
+				1,
+				100*time.Millisecond,
+				func() (R, error) {
+					return R{1, 2, 3, 4}, nil
+				},
+				func(r R) {
+					atomic.AddInt64(&dstrCall, 1)
+				},
+				true,
+			)
+			r, _ := p.Get()
+			p.Close()
+
+			require.False(t, p.Put(r))
+			require.Equal(t, int64(1), dstrCall)
+
+			err := p.TryReturn(R{5, 5, 5, 5})
+			require.ErrorIs(t, err, pool.ErrPoolClosed)
+			require.ErrorIs(t, err, pool.ErrResourceDestroyed)
+			require.Equal(t, int64(2), dstrCall)
+		})
+
+	t.Run(
+		"When pool is cleaned up twice, second call is no-op and idle objects are destructed once",
+		func(t *testing.T) {
+			t.Parallel()
+			dstrCall := int64(0)
+			p := pool.New(
+				2,
+				100*time.Millisecond,
+				func() (R, error) {
+					return R{1, 2, 3, 4}, nil
+				},
+				func(r R) {
+					atomic.AddInt64(&dstrCall, 1)
+				},
+				true,
+			)
+			r1, _ := p.Get()
+			r2, _ := p.Get()
+			p.Put(r1)
+			p.Put(r2)
+
+			require.NotPanics(t, func() {
+				defer p.Cleanup()
+				require.NoError(t, p.Cleanup())
+			})
+			require.Equal(t, int64(2), dstrCall)
+		})
+}
+
+func TestWaitingForReturn(t *testing.T) {
+	t.Parallel()
+
+	t.Run(
+		"When object is returned while someone waits for it, it is handed over before Put returns",
+		func(t *testing.T) {
+			t.Parallel()
+			p := pool.New(
+				1,
+				time.Minute,
+				func() (*int, error) {
+					return new(int), nil
+				},
+				func(r *int) {},
+				true,
+			)
+			defer p.Close()
+			r, _ := p.Get()
+
+			got := make(chan *int)
+			go func() {
+				r, _ := p.Get()
+				got <- r
+			}()
+			require.Eventually(t, func() bool {
+				return p.Stats().Waiting == 1
+			}, time.Second, time.Millisecond)
+
+			require.True(t, p.Put(r))
+			require.Zero(t, p.Idle(), "Object went straight to the waiter, not to idle ones")
+			require.Equal(t, int64(1), p.InUse())
+			require.Equal(t, r, <-got)
+		})
+
+	t.Run(
+		"When object is returned while someone waits for it, the waiter gets it before timeout",
+		func(t *testing.T) {
+			t.Parallel()
+			ctrCalls := int64(0)
+			p := pool.New(
+				1,
+				time.Minute,
+				func() (*int, error) {
+					atomic.AddInt64(&ctrCalls, 1)
+					return new(int), nil
+				},
+				func(r *int) {},
+				true,
+			)
+			defer p.Close()
+			r, _ := p.Get()
+
+			go func() {
+				time.Sleep(20 * time.Millisecond)
+				p.Put(r)
+			}()
+
+			got, err := p.GetTimeout(time.Second)
+			require.NoError(t, err)
+			require.Same(t, r, got)
+			require.Equal(t, int64(1), ctrCalls)
+		})
+
+	t.Run(
+		"When several objects are returned at once, every waiter gets one",
+		func(t *testing.T) {
+			t.Parallel()
+			const n = 5
+			p := pool.New(
+				n,
+				time.Minute,
+				func() (*int, error) {
+					return new(int), nil
+				},
+				func(r *int) {},
+				true,
+			)
+			defer p.Close()
+
+			taken := make([]*int, n)
+			for i := range taken {
+				taken[i], _ = p.Get()
+			}
+
+			errs := make(chan error, n)
+			for i := 0; i < n; i++ {
+				go func() {
+					_, err := p.GetTimeout(time.Second)
+					errs <- err
+				}()
+			}
+
+			time.Sleep(20 * time.Millisecond)
+			for _, r := range taken {
+				p.Put(r)
+			}
+			for i := 0; i < n; i++ {
+				require.NoError(t, <-errs)
+			}
+		})
+}
+
+func TestIdleKeys(t *testing.T) {
+	t.Parallel()
+
+	t.Run(
+		"When many objects are taken and returned in turns, pool keeps every one of them and destructs all on cleanup",
+		func(t *testing.T) {
+			t.Parallel()
+			const n = 10
+			dstrCall := int64(0)
+			p := pool.New(
+				n,
+				100*time.Millisecond,
+				func() (*int, error) {
+					return new(int), nil
+				},
+				func(r *int) {
+					atomic.AddInt64(&dstrCall, 1)
+				},
+				true,
+			)
+
+			for round := 0; round < 3; round++ {
+				taken := make([]*int, 0, n)
+				for i := 0; i < n; i++ {
+					r, err := p.Get()
+					require.NoError(t, err)
+					taken = append(taken, r)
+				}
+				for _, r := range taken {
+					require.True(t, p.Put(r))
+				}
+				require.Equal(t, int64(n), p.Idle())
+			}
+
+			p.Cleanup()
+			require.Equal(t, int64(n), dstrCall)
+		})
+}
+
+func TestCapacity(t *testing.T) {
+	t.Parallel()
+	type R struct{ a, b, c, d int }
+
+	t.Run(
+		"When pool is filled by both Get and Put, it never owns more objects than its capacity",
+		func(t *testing.T) {
+			t.Parallel()
+			const max = 5
+			ctrCalls := int64(0)
+			p := pool.New(
+				max,
+				10*time.Millisecond,
+				func() (R, error) {
+					atomic.AddInt64(&ctrCalls, 1)
+					return R{1, 2, 3, 4}, nil
+				},
+				func(r R) {},
+				true,
+			)
+			for i := 0; i < max-2; i++ {
+				_, _ = p.Get()
+			}
+			accepted := 0
+			for i := 0; i < max; i++ {
+				if p.Put(R{5, 5, 5, 5}) {
+					accepted++
+				}
+			}
+			require.Equal(t, 2, accepted)
+			require.Equal(t, int64(max), p.InUse()+p.Idle())
+
+			for i := 0; i < max; i++ {
+				_, _ = p.Get()
+			}
+			require.Equal(t, int64(max-2), ctrCalls)
+			require.Equal(t, int64(max), p.InUse()+p.Idle())
+		})
+}
+
+func TestTryReturn(t *testing.T) {
+	t.Parallel()
+	type R struct{ a, b, c, d int }
+
+	t.Run(
+		"When pool is full, TryReturn reports ErrPoolFull and leaves cleanup to the caller",
+		func(t *testing.T) {
+			t.Parallel()
+			dstrCall := int64(0)
+			p := pool.New(
+				1,
+				100*time.Millisecond,
+				func() (R, error) {
+					return R{1, 2, 3, 4}, nil
+				},
+				func(r R) {
+					atomic.AddInt64(&dstrCall, 1)
+				},
+				true,
+			)
+			require.NoError(t, p.TryReturn(R{5, 5, 5, 5}))
+			require.ErrorIs(t, p.TryReturn(R{5, 5, 5, 5}), pool.ErrPoolFull)
+			require.Equal(t, int64(0), dstrCall)
+		})
+
+	t.Run(
+		"When pool destructs returned object, TryReturn reports ErrResourceDestroyed",
+		func(t *testing.T) {
+			t.Parallel()
+			dstrCall := int64(0)
+			p := pool.New(
+				2,
+				100*time.Millisecond,
+				func() (R, error) {
+					return R{1, 2, 3, 4}, nil
+				},
+				func(r R) {
+					atomic.AddInt64(&dstrCall, 1)
+				},
+				true,
+				pool.WithMaxIdle[R](1),
+			)
+			require.NoError(t, p.TryReturn(R{5, 5, 5, 5}))
+			require.ErrorIs(t, p.TryReturn(R{5, 5, 5, 5}), pool.ErrResourceDestroyed)
+			require.Equal(t, int64(1), dstrCall)
+		})
+}
+
+func TestNewWithOptions(t *testing.T) {
+	t.Parallel()
+	type R struct{ a, b, c, d int }
+
+	t.Run(
+		"When no options are given, pool is unlimited",
+		func(t *testing.T) {
+			t.Parallel()
+			ctrCalls := int64(0)
+			p := pool.NewWithOptions(
+				func() (R, error) {
+					atomic.AddInt64(&ctrCalls, 1)
+					return R{1, 2, 3, 4}, nil
+				},
+				func(r R) {},
+			)
+			for i := 0; i < 10; i++ {
+				_, err := p.Get()
+				require.NoError(t, err)
+			}
+			require.Equal(t, int64(10), ctrCalls)
+		})
+
+	t.Run(
+		"When capacity and wait are given as options, pool honors them",
+		func(t *testing.T) {
+			t.Parallel()
+			p := pool.NewWithOptions(
+				func() (R, error) {
+					return R{1, 2, 3, 4}, nil
+				},
+				func(r R) {},
+				pool.WithMax[R](1),
+				pool.WithWait[R](10*time.Millisecond),
+				pool.WithPreallocate[R](true),
+			)
+			_, _ = p.Get()
+			_, err := p.Get()
+			require.ErrorIs(t, err, pool.ErrResourceUnavailable)
+		})
+}
+
+func TestLIFO(t *testing.T) {
+	t.Parallel()
+
+	newPool := func(opts ...pool.Option[int]) *pool.Pool[int] {
+		return pool.New(
+			-1,
+			100*time.Millisecond,
+			func() (int, error) {
+				return 0, nil
+			},
+			func(r int) {},
+			true,
+			opts...,
+		)
+	}
+
+	t.Run(
+		"When pool is LIFO, most recently returned object is handed out first",
+		func(t *testing.T) {
+			t.Parallel()
+			p := newPool(pool.WithLIFO[int]())
+			for i := 1; i <= 3; i++ {
+				p.Put(i)
+			}
+			for i := 3; i >= 1; i-- {
+				r, _ := p.Get()
+				require.Equal(t, i, r)
+			}
+		})
+
+	t.Run(
+		"When pool is not LIFO, least recently returned object is handed out first",
+		func(t *testing.T) {
+			t.Parallel()
+			p := newPool()
+			for i := 1; i <= 3; i++ {
+				p.Put(i)
+			}
+			for i := 1; i <= 3; i++ {
+				r, _ := p.Get()
+				require.Equal(t, i, r)
+			}
+		})
+}
+
+func TestFIFO(t *testing.T) {
+	t.Parallel()
+
+	t.Run(
+		"When Puts and Gets interleave, objects are handed out in order they were returned",
+		func(t *testing.T) {
+			t.Parallel()
+			p := pool.New(
+				-1,
+				100*time.Millisecond,
+				func() (int, error) {
+					return 0, nil
+				},
+				func(r int) {},
+				true,
+			)
+			var got []int
+			for i := 1; i <= 10; i++ {
+				p.Put(i)
+				if i%3 == 0 {
+					r, _ := p.Get()
+					got = append(got, r)
+				}
+			}
+			for p.Idle() > 0 {
+				r, _ := p.Get()
+				got = append(got, r)
+			}
+			require.Equal(t, []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, got)
+		})
+}
+
+func TestWarmup(t *testing.T) {
+	t.Parallel()
+	type R struct{ a, b, c, d int }
+
+	t.Run(
+		"When warm-up is requested, pool constructs idle objects up to its capacity right away",
+		func(t *testing.T) {
+			t.Parallel()
+			ctrCalls := int64(0)
+			p, err := pool.Open(
+				func() (R, error) {
+					atomic.AddInt64(&ctrCalls, 1)
+					return R{1, 2, 3, 4}, nil
+				},
+				func(r R) {},
+				pool.WithMax[R](3),
+				pool.WithWarmup[R](5),
+			)
+			require.NoError(t, err)
+			require.Equal(t, int64(3), ctrCalls)
+			require.Equal(t, int64(3), p.Idle())
+		})
+
+	t.Run(
+		"When construction fails during warm-up, Open returns error and destructs already constructed objects",
+		func(t *testing.T) {
+			t.Parallel()
+			errCtr := errors.New("ctr failed")
+			ctrCalls := int64(0)
+			dstrCall := int64(0)
+			p, err := pool.Open(
+				func() (R, error) {
+					if atomic.AddInt64(&ctrCalls, 1) == 3 {
+						return R{}, errCtr
+					}
+					return R{1, 2, 3, 4}, nil
+				},
+				func(r R) {
+					atomic.AddInt64(&dstrCall, 1)
+				},
+				pool.WithWarmup[R](5),
+			)
+			require.ErrorIs(t, err, errCtr)
+			require.Nil(t, p)
+			require.Equal(t, int64(2), dstrCall)
+		})
+}
+
+func TestMinIdle(t *testing.T) {
+	t.Parallel()
+	type R struct{ a, b, c, d int }
+
+	t.Run(
+		"When idle objects are taken, pool constructs replacements in background up to the minimum",
+		func(t *testing.T) {
+			t.Parallel()
+			p := pool.NewWithOptions(
+				func() (R, error) {
+					return R{1, 2, 3, 4}, nil
+				},
+				func(r R) {},
+				pool.WithMax[R](3),
+				pool.WithMinIdle[R](2),
+			)
+			defer p.Close()
+
+			require.Eventually(t, func() bool { return p.Idle() == 2 }, time.Second, time.Millisecond)
+
+			_, _ = p.Get()
+			_, _ = p.Get()
+			// Only one more fits into capacity
+			require.Eventually(t, func() bool { return p.Idle() == 1 }, time.Second, time.Millisecond)
+			require.Equal(t, int64(2), p.InUse())
+		})
+
+	t.Run(
+		"When idle objects kept for the minimum are idle for too long, reaper doesn't destruct them",
+		func(t *testing.T) {
+			t.Parallel()
+			dstrCall := int64(0)
+			p := pool.NewWithOptions(
+				func() (R, error) {
+					return R{1, 2, 3, 4}, nil
+				},
+				func(r R) {
+					atomic.AddInt64(&dstrCall, 1)
+				},
+				pool.WithMinIdle[R](1),
+				pool.WithMaxIdleTime[R](10*time.Millisecond),
+				pool.WithSweepInterval[R](5*time.Millisecond),
+			)
+			defer p.Close()
+			p.Put(R{5, 5, 5, 5})
+			p.Put(R{5, 5, 5, 5})
+
+			require.Eventually(t, func() bool { return p.Idle() == 1 }, time.Second, time.Millisecond)
+			destructed := atomic.LoadInt64(&dstrCall)
+			require.NotZero(t, destructed)
+
+			time.Sleep(50 * time.Millisecond)
+			require.Equal(t, destructed, atomic.LoadInt64(&dstrCall))
+			require.Equal(t, int64(1), p.Idle())
+		})
+}
+
+func TestRetry(t *testing.T) {
+	t.Parallel()
+	type R struct{ a, b, c, d int }
+	errTransient := errors.New("transient")
+	errFatal := errors.New("fatal")
+
+	t.Run(
+		"When CTR fails transiently, pool retries it before returning error",
+		func(t *testing.T) {
+			t.Parallel()
+			ctrCalls := int64(0)
+			p := pool.NewWithOptions(
+				func() (R, error) {
+					if atomic.AddInt64(&ctrCalls, 1) < 3 {
+						return R{}, errTransient
+					}
+					return R{1, 2, 3, 4}, nil
+				},
+				func(r R) {},
+				pool.WithRetry[R](3, time.Millisecond),
+			)
+			r, err := p.Get()
+			require.NoError(t, err)
+			require.Equal(t, R{1, 2, 3, 4}, r)
+			require.Equal(t, int64(3), ctrCalls)
+		})
+
+	t.Run(
+		"When CTR error is not retryable, pool returns it right away",
+		func(t *testing.T) {
+			t.Parallel()
+			ctrCalls := int64(0)
+			p := pool.NewWithOptions(
+				func() (R, error) {
+					atomic.AddInt64(&ctrCalls, 1)
+					return R{}, errFatal
+				},
+				func(r R) {},
+				pool.WithRetry[R](3, time.Millisecond),
+				pool.WithRetryIf[R](func(err error) bool {
+					return errors.Is(err, errTransient)
+				}),
+			)
+			_, err := p.Get()
+			require.ErrorIs(t, err, errFatal)
+			require.Equal(t, int64(1), ctrCalls)
+		})
+
+	t.Run(
+		"When retries would outlast wait budget, pool gives up with the last CTR error",
+		func(t *testing.T) {
+			t.Parallel()
+			ctrCalls := int64(0)
+			p := pool.NewWithOptions(
+				func() (R, error) {
+					atomic.AddInt64(&ctrCalls, 1)
+					return R{}, errTransient
+				},
+				func(r R) {},
+				pool.WithWait[R](20*time.Millisecond),
+				pool.WithRetry[R](100, 10*time.Millisecond),
+			)
+			start := time.Now()
+			_, err := p.Get()
+			require.ErrorIs(t, err, errTransient)
+			require.Less(t, time.Since(start), time.Second)
+			require.Less(t, atomic.LoadInt64(&ctrCalls), int64(100))
+		})
+}
+
+func TestContextFactory(t *testing.T) {
+	t.Parallel()
+	type R struct{ a, b, c, d int }
+
+	t.Run(
+		"When context of GetContext is cancelled, construction with context-aware CTR is cancelled too",
+		func(t *testing.T) {
+			t.Parallel()
+			p := pool.NewWithOptions(
+				nil,
+				func(r R) {},
+				pool.WithContextFactory(func(ctx context.Context) (R, error) {
+					<-ctx.Done()
+					return R{}, ctx.Err()
+				}),
+			)
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+
+			_, err := p.GetContext(ctx)
+			require.ErrorIs(t, err, context.DeadlineExceeded)
+			require.Equal(t, int64(0), p.InUse())
+		})
+
+	t.Run(
+		"When Get constructs with context-aware CTR, context is bounded by the wait time",
+		func(t *testing.T) {
+			t.Parallel()
+			p := pool.NewWithOptions(
+				nil,
+				func(r R) {},
+				pool.WithWait[R](10*time.Millisecond),
+				pool.WithContextFactory(func(ctx context.Context) (R, error) {
+					_, ok := ctx.Deadline()
+					require.True(t, ok)
+					return R{1, 2, 3, 4}, nil
+				}),
+			)
+			r, err := p.Get()
+			require.NoError(t, err)
+			require.Equal(t, R{1, 2, 3, 4}, r)
+		})
+
+	t.Run(
+		"When CTR outlasts the wait time, Get fails with ErrCreateTimeout wrapping CTR error",
+		func(t *testing.T) {
+			t.Parallel()
+			p := pool.NewWithOptions(
+				nil,
+				func(r R) {},
+				pool.WithWait[R](10*time.Millisecond),
+				pool.WithContextFactory(func(ctx context.Context) (R, error) {
+					<-ctx.Done()
+					return R{}, ctx.Err()
+				}),
+			)
+			_, err := p.Get()
+			require.ErrorIs(t, err, pool.ErrCreateTimeout)
+			require.ErrorIs(t, err, context.DeadlineExceeded)
+			require.NotErrorIs(t, err, pool.ErrResourceUnavailable, "Slow CTR is not lack of capacity")
+			require.Equal(t, int64(0), p.InUse())
+		})
+}
+
+func TestDestructorErr(t *testing.T) {
+	t.Parallel()
+
+	t.Run(
+		"When destructors fail during cleanup, Cleanup returns all their errors",
+		func(t *testing.T) {
+			t.Parallel()
+			errOdd := errors.New("odd")
+			errThree := errors.New("three")
+			p := pool.NewWithOptions(
+				func() (int, error) {
+					return 0, nil
+				},
+				nil,
+				pool.WithDestructorErr(func(r int) error {
+					switch {
+					case r == 3:
+						return errThree
+					case r%2 == 1:
+						return errOdd
+					}
+					return nil
+				}),
+			)
+			for i := 0; i < 4; i++ {
+				p.Put(i)
+			}
+
+			err := p.Cleanup()
+			require.ErrorIs(t, err, errOdd)
+			require.ErrorIs(t, err, errThree)
+			require.NoError(t, p.Cleanup(), "Pool is already cleaned up")
+		})
+}
+
+func TestHooks(t *testing.T) {
+	t.Parallel()
+	type R struct{ a, b, c, d int }
+
+	t.Run(
+		"When objects are taken and accepted back, pool calls hooks for both idle and fresh objects",
+		func(t *testing.T) {
+			t.Parallel()
+			gets, puts := int64(0), int64(0)
+			p := pool.New(
+				1,
+				100*time.Millisecond,
+				func() (R, error) {
+					return R{1, 2, 3, 4}, nil
+				},
+				func(r R) {},
+				true,
+				pool.WithOnGet(func(r R) {
+					atomic.AddInt64(&gets, 1)
+				}),
+				pool.WithOnPut(func(r R) {
+					atomic.AddInt64(&puts, 1)
+				}),
+			)
+			r, _ := p.Get() // fresh
+			p.Put(r)
+			r, _ = p.Get() // idle
+			_, _ = p.Get() // times out
+			p.Put(r)
+			p.Put(R{5, 5, 5, 5}) // refused
+
+			require.Equal(t, int64(2), gets)
+			require.Equal(t, int64(2), puts)
+		})
+
+	t.Run(
+		"When Get fails with ErrResourceUnavailable, pool calls timeout hook",
+		func(t *testing.T) {
+			t.Parallel()
+			timeouts := int64(0)
+			p := pool.New(
+				1,
+				10*time.Millisecond,
+				func() (R, error) {
+					return R{1, 2, 3, 4}, nil
+				},
+				func(r R) {},
+				true,
+				pool.WithOnTimeout[R](func() {
+					atomic.AddInt64(&timeouts, 1)
+				}),
+			)
+			_, _ = p.Get()
+			require.Zero(t, timeouts)
+
+			_, err := p.Get()
+			require.ErrorIs(t, err, pool.ErrResourceUnavailable)
+			_, err = p.GetTimeout(0)
+			require.ErrorIs(t, err, pool.ErrResourceUnavailable)
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			_, _ = p.GetContext(ctx)
+			require.Equal(t, int64(2), timeouts, "Context errors don't trigger hook")
+		})
+}
+
+func TestLogger(t *testing.T) {
+	t.Parallel()
+
+	t.Run(
+		"When logger is given, pool logs construction, destruction, timeouts and refused Puts",
+		func(t *testing.T) {
+			t.Parallel()
+			var buf bytes.Buffer
+			logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+			p := pool.New(
+				1,
+				time.Millisecond,
+				func() (int, error) {
+					return 0, nil
+				},
+				func(r int) {},
+				true,
+				pool.WithLogger[int](logger),
+				pool.WithMaxIdle[int](0),
+			)
+			r, _ := p.Get()
+			_, _ = p.Get() // times out
+			p.Put(r)       // destructed due to max idle
+
+			out := buf.String()
+			require.Contains(t, out, "pool: constructed resource")
+			require.Contains(t, out, "pool: Get timed out")
+			require.Contains(t, out, "pool: destructed resource")
+			require.Contains(t, out, "pool: refused Put")
+		})
+}
+
+func TestGetFresh(t *testing.T) {
+	t.Parallel()
+
+	t.Run(
+		"When resource is constructed, GetFresh reports it, and doesn't for reused ones",
+		func(t *testing.T) {
+			t.Parallel()
+			p := pool.New(
+				1,
+				time.Second,
+				func() (int, error) {
+					return 0, nil
+				},
+				func(r int) {},
+				true,
+			)
+			r, fresh, err := p.GetFresh()
+			require.NoError(t, err)
+			require.True(t, fresh)
+			p.Put(r)
+
+			r, fresh, err = p.GetFresh()
+			require.NoError(t, err)
+			require.False(t, fresh, "Idle resource is reused")
+
+			go func() {
+				time.Sleep(10 * time.Millisecond)
+				p.Put(r)
+			}()
+			_, fresh, err = p.GetFresh()
+			require.NoError(t, err)
+			require.False(t, fresh, "Returned resource is reused")
+		})
+}
+
+func TestPanics(t *testing.T) {
+	t.Parallel()
+
+	t.Run(
+		"When constructor panics, Get returns error and pool doesn't count resource",
+		func(t *testing.T) {
+			t.Parallel()
+			p := pool.New(
+				1,
+				10*time.Millisecond,
+				func() (int, error) {
+					panic("boom")
+				},
+				func(r int) {},
+				true,
+			)
+			_, err := p.Get()
+			require.ErrorIs(t, err, pool.ErrPanicked)
+			require.Zero(t, p.InUse())
+
+			_, err = p.Get()
+			require.ErrorIs(t, err, pool.ErrPanicked, "Capacity is not leaked")
+		})
+
+	t.Run(
+		"When destructor panics, Cleanup returns error and destructs the rest",
+		func(t *testing.T) {
+			t.Parallel()
+			destructed := int64(0)
+			p := pool.New(
+				2,
+				10*time.Millisecond,
+				func() (int, error) {
+					return 0, nil
+				},
+				func(r int) {
+					if atomic.AddInt64(&destructed, 1) == 1 {
+						panic("boom")
+					}
+				},
+				true,
+			)
+			r1, _ := p.Get()
+			r2, _ := p.Get()
+			p.Put(r1)
+			p.Put(r2)
+
+			require.ErrorIs(t, p.Cleanup(), pool.ErrPanicked)
+			require.Equal(t, int64(2), destructed)
+		})
+}
+
+func TestGetN(t *testing.T) {
+	t.Parallel()
+
+	t.Run(
+		"When enough objects are available, GetN returns all of them",
+		func(t *testing.T) {
+			t.Parallel()
+			p := pool.New(
+				3,
+				100*time.Millisecond,
+				func() (*int, error) {
+					return new(int), nil
+				},
+				func(r *int) {},
+				true,
+			)
+			rs, err := p.GetN(3)
+			require.NoError(t, err)
+			require.Len(t, rs, 3)
+			require.Equal(t, int64(3), p.InUse())
+
+			_, err = p.GetN(4)
+			require.ErrorIs(t, err, pool.ErrResourceUnavailable, "Pool can never hold 4 objects")
+		})
+
+	t.Run(
+		"When number of objects is negative, GetN fails instead of panicking",
+		func(t *testing.T) {
+			t.Parallel()
+			p := pool.New(
+				-1,
+				100*time.Millisecond,
+				func() (*int, error) {
+					return new(int), nil
+				},
+				func(r *int) {},
+				true,
+			)
+			rs, err := p.GetN(-1)
+			require.Error(t, err)
+			require.Nil(t, rs)
+			require.Zero(t, p.InUse())
+		})
+
+	t.Run(
+		"When full set can't be acquired in time, GetN puts back acquired objects",
+		func(t *testing.T) {
+			t.Parallel()
+			p := pool.New(
+				3,
+				50*time.Millisecond,
+				func() (*int, error) {
+					return new(int), nil
+				},
+				func(r *int) {},
+				true,
+			)
+			held, _ := p.Get()
+
+			_, err := p.GetN(3)
+			require.ErrorIs(t, err, pool.ErrResourceUnavailable)
+			require.Equal(t, int64(1), p.InUse())
+			require.Equal(t, int64(2), p.Idle())
+
+			p.Put(held)
+			rs, err := p.GetN(3)
+			require.NoError(t, err)
+			require.Len(t, rs, 3)
+		})
+
+	t.Run(
+		"When two callers collect batches concurrently, both eventually succeed",
+		func(t *testing.T) {
+			t.Parallel()
+			p := pool.New(
+				2,
+				50*time.Millisecond,
+				func() (*int, error) {
+					return new(int), nil
+				},
+				func(r *int) {},
+				true,
+			)
+			errs := make(chan error)
+			for i := 0; i < 2; i++ {
+				go func() {
+					for {
+						rs, err := p.GetN(2)
+						if errors.Is(err, pool.ErrResourceUnavailable) {
+							continue
+						}
+						if err == nil {
+							time.Sleep(time.Millisecond)
+							for _, r := range rs {
+								p.Put(r)
+							}
+						}
+						errs <- err
+						return
+					}
+				}()
+			}
+			require.NoError(t, <-errs)
+			require.NoError(t, <-errs)
+		})
+}
+
+func TestPutN(t *testing.T) {
+	t.Parallel()
+
+	t.Run(
+		"When more objects are put back than pool has room for, PutN destructs the rest",
+		func(t *testing.T) {
+			t.Parallel()
+			dstrCall, puts := int64(0), int64(0)
+			p := pool.New(
+				3,
+				100*time.Millisecond,
+				func() (*int, error) {
+					return new(int), nil
+				},
+				func(r *int) {
+					atomic.AddInt64(&dstrCall, 1)
+				},
+				true,
+				pool.WithOnPut(func(r *int) {
+					atomic.AddInt64(&puts, 1)
+				}),
+			)
+			rs, err := p.GetN(2)
+			require.NoError(t, err)
+
+			accepted := p.PutN(append(rs, new(int), new(int)))
+			require.Equal(t, 3, accepted, "Both borrowed objects and one foreign fit")
+			require.Equal(t, int64(1), dstrCall)
+			require.Equal(t, int64(3), puts)
+			require.Equal(t, int64(3), p.Idle())
+			require.Zero(t, p.InUse())
+		})
+}
+
+func TestResetIdle(t *testing.T) {
+	t.Parallel()
+
+	t.Run(
+		"When idle objects are reset, they are destructed and borrowed ones are left alone",
+		func(t *testing.T) {
+			t.Parallel()
+			ctrCalls, dstrCall := int64(0), int64(0)
+			p := pool.New(
+				3,
+				100*time.Millisecond,
+				func() (*int, error) {
+					atomic.AddInt64(&ctrCalls, 1)
+					return new(int), nil
+				},
+				func(r *int) {
+					atomic.AddInt64(&dstrCall, 1)
+				},
+				true,
+			)
+			rs, _ := p.GetN(3)
+			p.Put(rs[0])
+			p.Put(rs[1])
+
+			require.NoError(t, p.ResetIdle())
+			require.Equal(t, int64(2), dstrCall)
+			require.Zero(t, p.Idle())
+			require.Equal(t, int64(1), p.InUse())
+
+			_, err := p.Get()
+			require.NoError(t, err)
+			require.Equal(t, int64(4), ctrCalls, "Fresh object is constructed")
+			require.True(t, p.Put(rs[2]), "Borrowed object is still accepted back")
+		})
+}
+
+func TestDrainInto(t *testing.T) {
+	t.Parallel()
+
+	t.Run(
+		"When idle objects are drained into caller, they are not destructed and pool constructs fresh ones",
+		func(t *testing.T) {
+			t.Parallel()
+			ctrCalls, dstrCalls := int64(0), int64(0)
+			p := pool.New(
+				3,
+				100*time.Millisecond,
+				func() (*int, error) {
+					atomic.AddInt64(&ctrCalls, 1)
+					return new(int), nil
+				},
+				func(r *int) {
+					atomic.AddInt64(&dstrCalls, 1)
+				},
+				true,
+			)
+			rs, _ := p.GetN(3)
+			p.Put(rs[0])
+			p.Put(rs[1])
+
+			require.Equal(t, []*int{rs[0], rs[1]}, p.DrainInto())
+			require.Zero(t, dstrCalls)
+			require.Zero(t, p.Idle())
+			require.Equal(t, int64(1), p.InUse())
+			require.Empty(t, p.DrainInto())
+
+			_, err := p.GetN(2)
+			require.NoError(t, err)
+			require.Equal(t, int64(5), ctrCalls, "Fresh objects are constructed")
+			require.True(t, p.Put(rs[2]), "Borrowed object is still accepted back")
+		})
+}
+
+func TestNoTimeout(t *testing.T) {
+	t.Parallel()
+
+	newFullPool := func() (*pool.Pool[*int], *int) {
+		p := pool.New(
+			1,
+			-1,
+			func() (*int, error) {
+				return new(int), nil
+			},
+			func(r *int) {},
+			true,
+		)
+		r, _ := p.Get()
+		return p, r
+	}
+
+	t.Run(
+		"When wait is negative, Get blocks until object is returned",
+		func(t *testing.T) {
+			t.Parallel()
+			p, r := newFullPool()
+
+			got := make(chan *int)
+			go func() {
+				r, _ := p.Get()
+				got <- r
+			}()
+
+			select {
+			case <-got:
+				t.Fatal("Get must block while pool is full")
+			case <-time.After(50 * time.Millisecond):
+			}
+			p.Put(r)
+			require.Equal(t, r, <-got)
+		})
+
+	t.Run(
+		"When wait is negative and pool is closed, blocked Get fails with ErrPoolClosed",
+		func(t *testing.T) {
+			t.Parallel()
+			p, _ := newFullPool()
+
+			errs := make(chan error)
+			go func() {
+				_, err := p.Get()
+				errs <- err
+			}()
+			time.Sleep(20 * time.Millisecond)
+			p.Close()
+			require.ErrorIs(t, <-errs, pool.ErrPoolClosed)
+		})
+}
+
+func TestFairness(t *testing.T) {
+	t.Parallel()
+
+	t.Run(
+		"When several callers wait for objects, they are served in arrival order",
+		func(t *testing.T) {
+			t.Parallel()
+			p := pool.New(
+				1,
+				time.Minute,
+				func() (*int, error) {
+					return new(int), nil
+				},
+				func(r *int) {},
+				true,
+			)
+			r, _ := p.Get()
+
+			const waiters = 5
+			served := make(chan int, waiters)
+			for i := 0; i < waiters; i++ {
+				go func(i int) {
+					r, err := p.Get()
+					require.NoError(t, err)
+					served <- i
+					p.Put(r)
+				}(i)
+				time.Sleep(10 * time.Millisecond)
+			}
+
+			p.Put(r)
+			for i := 0; i < waiters; i++ {
+				require.Equal(t, i, <-served)
+			}
+		})
+
+	t.Run(
+		"When callers wait for objects, newcomers don't take returned ones ahead of them",
+		func(t *testing.T) {
+			t.Parallel()
+			p := pool.New(
+				1,
+				time.Minute,
+				func() (*int, error) {
+					return new(int), nil
+				},
+				func(r *int) {},
+				true,
+			)
+			r, _ := p.Get()
+
+			got := make(chan *int)
+			go func() {
+				r, _ := p.Get()
+				got <- r
+			}()
+			time.Sleep(20 * time.Millisecond)
+
+			p.Put(r)
+			_, ok, err := p.TryGet()
+			require.NoError(t, err)
+			if ok {
+				t.Fatal("Newcomer must not take object from the waiter")
+			}
+			require.Equal(t, r, <-got)
+		})
+
+	t.Run(
+		"When waiters time out, idle objects are available to newcomers again",
+		func(t *testing.T) {
+			t.Parallel()
+			p := pool.New(
+				1,
+				10*time.Millisecond,
+				func() (*int, error) {
+					return new(int), nil
+				},
+				func(r *int) {},
+				true,
+			)
+			r, _ := p.Get()
+			_, err := p.Get()
+			require.ErrorIs(t, err, pool.ErrResourceUnavailable)
+
+			p.Put(r)
+			require.Eventually(t, func() bool {
+				_, ok, _ := p.TryGet()
+				return ok
+			}, time.Second, time.Millisecond)
+		})
+}
+
+func TestMaxConcurrentCreates(t *testing.T) {
+	t.Parallel()
+
+	t.Run(
+		"When burst of Get calls arrives, pool runs no more constructions at once than allowed",
+		func(t *testing.T) {
+			t.Parallel()
+			inFlight, peak := int64(0), int64(0)
+			p := pool.New(
+				-1,
+				time.Second,
+				func() (*int, error) {
+					n := atomic.AddInt64(&inFlight, 1)
+					for {
+						old := atomic.LoadInt64(&peak)
+						if n <= old || atomic.CompareAndSwapInt64(&peak, old, n) {
+							break
+						}
+					}
+					time.Sleep(10 * time.Millisecond)
+					atomic.AddInt64(&inFlight, -1)
+					return new(int), nil
+				},
+				func(r *int) {},
+				true,
+				pool.WithMaxConcurrentCreates[*int](2),
+			)
+
+			errs := make(chan error)
+			for i := 0; i < 10; i++ {
+				go func() {
+					_, err := p.Get()
+					errs <- err
+				}()
+			}
+			for i := 0; i < 10; i++ {
+				require.NoError(t, <-errs)
+			}
+			require.Equal(t, int64(2), peak)
+			require.Equal(t, int64(10), p.TotalCreated())
+		})
+
+	t.Run(
+		"When construction slot isn't freed in time, Get fails with ErrResourceUnavailable",
+		func(t *testing.T) {
+			t.Parallel()
+			release := make(chan struct{})
+			p := pool.New(
+				-1,
+				20*time.Millisecond,
+				func() (*int, error) {
+					<-release
+					return new(int), nil
+				},
+				func(r *int) {},
+				true,
+				pool.WithMaxConcurrentCreates[*int](1),
+			)
+			defer close(release)
+
+			go func() { _, _ = p.GetTimeout(time.Minute) }()
+			time.Sleep(10 * time.Millisecond)
+
+			_, err := p.Get()
+			require.ErrorIs(t, err, pool.ErrResourceUnavailable)
+			require.Equal(t, int64(1), p.InUse(), "Failed construction is not counted")
+		})
+
+	t.Run(
+		"When construction slot is busy, callers who don't wait fail right away",
+		func(t *testing.T) {
+			t.Parallel()
+			started, release := make(chan struct{}), make(chan struct{})
+			p := pool.New(
+				-1,
+				0,
+				func() (*int, error) {
+					close(started)
+					<-release
+					return new(int), nil
+				},
+				func(r *int) {},
+				true,
+				pool.WithMaxConcurrentCreates[*int](1),
+			)
+			defer close(release)
+
+			go func() { _, _ = p.GetTimeout(time.Minute) }()
+			<-started
+
+			start := time.Now()
+			_, err := p.Get()
+			require.ErrorIs(t, err, pool.ErrResourceUnavailable)
+			_, err = p.GetTimeout(0)
+			require.ErrorIs(t, err, pool.ErrResourceUnavailable)
+			_, ok, err := p.TryGet()
+			require.NoError(t, err)
+			require.False(t, ok)
+			require.Less(t, time.Since(start), 500*time.Millisecond)
+			require.Equal(t, int64(1), p.InUse(), "Capacity is given back")
+		})
+}
+
+func TestHandoff(t *testing.T) {
+	t.Parallel()
+
+	t.Run(
+		"When object is put back while callers wait for construction slot, one of them takes it instead",
+		func(t *testing.T) {
+			t.Parallel()
+			release := make(chan struct{})
+			p := pool.New(
+				-1,
+				time.Second,
+				func() (*int, error) {
+					<-release
+					return new(int), nil
+				},
+				func(r *int) {},
+				true,
+				pool.WithMaxConcurrentCreates[*int](1),
+			)
+			defer close(release)
+
+			// Foreign object, so that pool has something to hand off.
+			foreign := new(int)
+			go func() { _, _ = p.Get() }() // holds the only construction slot
+			time.Sleep(10 * time.Millisecond)
+
+			got := make(chan *int)
+			go func() {
+				r, fresh, err := p.GetFresh()
+				require.NoError(t, err)
+				require.False(t, fresh)
+				got <- r
+			}()
+			time.Sleep(10 * time.Millisecond)
+
+			require.True(t, p.Put(foreign))
+			require.Equal(t, foreign, <-got)
+			require.Zero(t, p.Idle())
+			require.Equal(t, int64(2), p.InUse(), "Object under construction and handed off one")
+		})
+}
+
+func TestDo(t *testing.T) {
+	t.Parallel()
+
+	t.Run(
+		"When fn fails, Do returns its error and puts object back",
+		func(t *testing.T) {
+			t.Parallel()
+			errFn := errors.New("fn")
+			p := pool.New(
+				1,
+				10*time.Millisecond,
+				func() (*int, error) {
+					return new(int), nil
+				},
+				func(r *int) {},
+				true,
+			)
+			var used *int
+			err := p.Do(func(r *int) error {
+				used = r
+				return errFn
+			})
+			require.ErrorIs(t, err, errFn)
+			require.Equal(t, int64(1), p.Idle())
+
+			require.NoError(t, p.Do(func(r *int) error {
+				require.Equal(t, used, r, "Object is reused")
+				return nil
+			}))
+		})
+
+	t.Run(
+		"When object can't be acquired, Do returns the error without calling fn",
+		func(t *testing.T) {
+			t.Parallel()
+			p := pool.New(
+				1,
+				10*time.Millisecond,
+				func() (*int, error) {
+					return new(int), nil
+				},
+				func(r *int) {},
+				true,
+			)
+			_, _ = p.Get()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			err := p.DoContext(ctx, func(r *int) error {
+				t.Fatal("fn must not be called")
+				return nil
+			})
+			require.ErrorIs(t, err, context.Canceled)
+			require.ErrorIs(t, p.Do(func(r *int) error { return nil }), pool.ErrResourceUnavailable)
+		})
+}
+
+func TestDiscard(t *testing.T) {
+	t.Parallel()
+
+	t.Run(
+		"When borrowed object is discarded, pool destructs it and frees its capacity",
+		func(t *testing.T) {
+			t.Parallel()
+			dstrCall := int64(0)
+			p := pool.New(
+				1,
+				10*time.Millisecond,
+				func() (*int, error) {
+					return new(int), nil
+				},
+				func(r *int) {
+					atomic.AddInt64(&dstrCall, 1)
+				},
+				true,
+			)
+			r, _ := p.Get()
+			p.Discard(r)
+			require.Equal(t, int64(1), dstrCall)
+			require.Zero(t, p.InUse())
+			require.Zero(t, p.Idle())
+
+			fresh, err := p.Get()
+			require.NoError(t, err)
+			require.NotSame(t, r, fresh)
+		})
+
+	t.Run(
+		"When borrowed object is discarded while someone waits, the waiter gets a fresh one",
+		func(t *testing.T) {
+			t.Parallel()
+			p := pool.New(
+				1,
+				time.Minute,
+				func() (*int, error) {
+					return new(int), nil
+				},
+				func(r *int) {},
+				true,
+			)
+			r, _ := p.Get()
+
+			type got struct {
+				r     *int
+				fresh bool
+			}
+			gots := make(chan got)
+			go func() {
+				r, fresh, err := p.GetFresh()
+				require.NoError(t, err)
+				gots <- got{r, fresh}
+			}()
+			time.Sleep(20 * time.Millisecond)
+
+			p.Discard(r)
+			g := <-gots
+			require.NotSame(t, r, g.r)
+			require.True(t, g.fresh)
+			require.Equal(t, int64(2), p.TotalCreated())
+		})
+
+	t.Run(
+		"When object is put back with health flag, PutOrDiscard pools healthy one and discards dead one",
+		func(t *testing.T) {
+			t.Parallel()
+			dstrCall := int64(0)
+			p := pool.New(
+				2,
+				10*time.Millisecond,
+				func() (*int, error) {
+					return new(int), nil
+				},
+				func(r *int) {
+					atomic.AddInt64(&dstrCall, 1)
+				},
+				true,
+			)
+			healthy, _ := p.Get()
+			dead, _ := p.Get()
+
+			require.True(t, p.PutOrDiscard(healthy, true))
+			require.False(t, p.PutOrDiscard(dead, false))
+			require.Equal(t, int64(1), dstrCall)
+			require.Equal(t, int64(1), p.Idle())
+			require.Zero(t, p.InUse())
+
+			rs, err := p.GetN(2)
+			require.NoError(t, err, "Capacity of dead object is freed")
+			require.Len(t, rs, 2)
+		})
+}
+
+func TestMaxUses(t *testing.T) {
+	t.Parallel()
+
+	t.Run(
+		"When object was handed out max times, pool destructs it on Put and constructs a replacement",
+		func(t *testing.T) {
+			t.Parallel()
+			dstrCall := int64(0)
+			p := pool.New(
+				1,
+				10*time.Millisecond,
+				func() (*int, error) {
+					return new(int), nil
+				},
+				func(r *int) {
+					atomic.AddInt64(&dstrCall, 1)
+				},
+				true,
+				pool.WithMaxUses[*int](2),
+			)
+			first, _ := p.Get()
+			require.True(t, p.Put(first))
+			r, _ := p.Get()
+			require.Same(t, first, r)
+			require.False(t, p.Put(r), "Second use is the last one")
+			require.Equal(t, int64(1), dstrCall)
+
+			r, _ = p.Get()
+			require.NotSame(t, first, r)
+			require.Equal(t, int64(2), p.TotalCreated())
+		})
+}
+
+func TestLeakedSince(t *testing.T) {
+	t.Parallel()
+
+	t.Run(
+		"When objects are held longer than threshold, pool reports them as leaked",
+		func(t *testing.T) {
+			t.Parallel()
+			p := pool.New(
+				3,
+				10*time.Millisecond,
+				func() (*int, error) {
+					return new(int), nil
+				},
+				func(r *int) {},
+				true,
+			)
+			old, _ := p.Get()
+			leaked, _ := p.Get()
+			time.Sleep(20 * time.Millisecond)
+			_, _ = p.Get()
+			p.Put(old)
+
+			require.Equal(t, 1, p.LeakedSince(20*time.Millisecond))
+			require.Equal(t, 2, p.LeakedSince(0))
+			p.Put(leaked)
+			require.Zero(t, p.LeakedSince(20*time.Millisecond))
+		})
+}
+
+func TestOutstandingBorrows(t *testing.T) {
+	t.Parallel()
+
+	t.Run(
+		"When objects are borrowed, pool reports how long each is held, longest first",
+		func(t *testing.T) {
+			t.Parallel()
+			clock := newFakeClock()
+			p := pool.NewWithOptions(
+				func() (*int, error) { return new(int), nil },
+				func(r *int) {},
+				pool.WithClock[*int](clock),
+			)
+			require.Empty(t, p.OutstandingBorrows())
+
+			first, _ := p.Get()
+			clock.Advance(time.Minute)
+			second, _ := p.Get()
+			clock.Advance(time.Second)
+			returned, _ := p.Get()
+			p.Put(returned)
+
+			require.Equal(t, []time.Duration{time.Minute + time.Second, time.Second}, p.OutstandingBorrows())
+			p.Put(first)
+			p.Put(second)
+			require.Empty(t, p.OutstandingBorrows())
+		})
+}
+
+func TestReset(t *testing.T) {
+	t.Parallel()
+
+	t.Run(
+		"When reused object is handed out, pool resets it first and leaves fresh ones alone",
+		func(t *testing.T) {
+			t.Parallel()
+			resets := int64(0)
+			p := pool.New(
+				1,
+				10*time.Millisecond,
+				func() (*bytes.Buffer, error) {
+					return bytes.NewBufferString("fresh"), nil
+				},
+				func(b *bytes.Buffer) {},
+				true,
+				pool.WithReset(func(b *bytes.Buffer) *bytes.Buffer {
+					atomic.AddInt64(&resets, 1)
+					b.Reset()
+					return b
+				}),
+			)
+			b, _ := p.Get()
+			require.Equal(t, "fresh", b.String())
+			b.WriteString(" & dirty")
+			p.Put(b)
+
+			b, _ = p.Get()
+			require.Empty(t, b.String())
+			require.Equal(t, int64(1), resets)
+		})
+
+	t.Run(
+		"When reset replaces object, replacement is recognized when put back",
+		func(t *testing.T) {
+			t.Parallel()
+			p := pool.New(
+				1,
+				10*time.Millisecond,
+				func() (int, error) {
+					return 5, nil
+				},
+				func(r int) {},
+				true,
+				pool.WithReset(func(r int) int {
+					return 0
+				}),
+			)
+			r, _ := p.Get()
+			p.Put(r)
+
+			r, _ = p.Get()
+			require.Zero(t, r)
+			require.True(t, p.Put(r))
+			require.Equal(t, int64(1), p.Idle())
+			require.Zero(t, p.InUse())
+		})
+}
+
+func TestNewWithContext(t *testing.T) {
+	t.Parallel()
+
+	t.Run(
+		"When context is cancelled, pool closes itself",
+		func(t *testing.T) {
+			t.Parallel()
+			dstrCall := int64(0)
+			ctx, cancel := context.WithCancel(context.Background())
+			p := pool.NewWithContext(
+				ctx,
+				func() (*int, error) {
+					return new(int), nil
+				},
+				func(r *int) {
+					atomic.AddInt64(&dstrCall, 1)
+				},
+				pool.WithWarmup[*int](2),
+			)
+			r, err := p.Get()
+			require.NoError(t, err)
+
+			cancel()
+			require.Eventually(t, func() bool {
+				_, err := p.Get()
+				return errors.Is(err, pool.ErrPoolClosed)
+			}, time.Second, time.Millisecond)
+			require.Equal(t, int64(1), atomic.LoadInt64(&dstrCall), "Idle object is destructed")
+			require.False(t, p.Put(r))
+		})
+}
+
+func TestCreateRateLimit(t *testing.T) {
+	t.Parallel()
+
+	t.Run(
+		"When burst is used up, Get waits for its turn to construct",
+		func(t *testing.T) {
+			t.Parallel()
+			p := pool.NewWithOptions(
+				func() (*int, error) { return new(int), nil },
+				func(r *int) {},
+				pool.WithWait[*int](time.Second),
+				pool.WithCreateRateLimit[*int](20),
+			)
+			for i := 0; i < 20; i++ {
+				_, err := p.Get()
+				require.NoError(t, err)
+			}
+
+			start := time.Now()
+			_, err := p.Get()
+			require.NoError(t, err)
+			require.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+		})
+
+	t.Run(
+		"When turn to construct doesn't come within timeout, Get fails",
+		func(t *testing.T) {
+			t.Parallel()
+			ctrCalls := int64(0)
+			p := pool.NewWithOptions(
+				func() (*int, error) {
+					atomic.AddInt64(&ctrCalls, 1)
+					return new(int), nil
+				},
+				func(r *int) {},
+				pool.WithWait[*int](10*time.Millisecond),
+				pool.WithCreateRateLimit[*int](1),
+			)
+			_, err := p.Get()
+			require.NoError(t, err)
+
+			_, err = p.Get()
+			require.ErrorIs(t, err, pool.ErrResourceUnavailable)
+			require.Equal(t, int64(1), atomic.LoadInt64(&ctrCalls))
+		})
+
+	t.Run(
+		"When burst is used up, callers who don't wait fail right away",
+		func(t *testing.T) {
+			t.Parallel()
+			p := pool.NewWithOptions(
+				func() (*int, error) { return new(int), nil },
+				func(r *int) {},
+				pool.WithWait[*int](0),
+				pool.WithCreateRateLimit[*int](0.5),
+			)
+			_, err := p.Get()
+			require.NoError(t, err)
+
+			start := time.Now()
+			_, err = p.Get()
+			require.ErrorIs(t, err, pool.ErrResourceUnavailable)
+			_, ok, err := p.TryGet()
+			require.NoError(t, err)
+			require.False(t, ok)
+			require.Less(t, time.Since(start), 500*time.Millisecond)
+			require.Equal(t, int64(1), p.InUse(), "Capacity is given back")
+		})
+
+	t.Run(
+		"When resource is idle, Get doesn't wait for rate limit",
+		func(t *testing.T) {
+			t.Parallel()
+			p := pool.NewWithOptions(
+				func() (*int, error) { return new(int), nil },
+				func(r *int) {},
+				pool.WithWait[*int](10*time.Millisecond),
+				pool.WithCreateRateLimit[*int](1),
+			)
+			r, err := p.Get()
+			require.NoError(t, err)
+			require.True(t, p.Put(r))
+
+			for i := 0; i < 5; i++ {
+				r, err := p.Get()
+				require.NoError(t, err)
+				require.True(t, p.Put(r))
+			}
+		})
+}
+
+func TestGetPriority(t *testing.T) {
+	t.Parallel()
+
+	t.Run(
+		"When pool is full, returned resources go to waiters of higher priority first, then in arrival order",
+		func(t *testing.T) {
+			t.Parallel()
+			p := pool.New(
+				1,
+				time.Minute,
+				func() (*int, error) { return new(int), nil },
+				func(r *int) {},
+				true,
+			)
+			defer p.Close()
+			r, err := p.Get()
+			require.NoError(t, err)
+
+			served := make(chan string, 4)
+			wait := func(name string, priority int) {
+				waiting := p.Stats().Waiting
+				go func() {
+					r, err := p.GetPriority(priority)
+					if err == nil {
+						served <- name
+						p.Put(r)
+					}
+				}()
+				require.Eventually(t, func() bool {
+					return p.Stats().Waiting == waiting+1
+				}, time.Second, time.Millisecond)
+			}
+			wait("batch", 0)
+			wait("interactive-1", 10)
+			wait("background", -5)
+			wait("interactive-2", 10)
+
+			require.True(t, p.Put(r))
+			order := make([]string, 0, 4)
+			for i := 0; i < 4; i++ {
+				order = append(order, <-served)
+			}
+			require.Equal(t, []string{"interactive-1", "interactive-2", "batch", "background"}, order)
+		})
+
+	t.Run(
+		"When pool has idle resource, priority doesn't matter",
+		func(t *testing.T) {
+			t.Parallel()
+			p := pool.New(
+				1,
+				time.Minute,
+				func() (*int, error) { return new(int), nil },
+				func(r *int) {},
+				true,
+			)
+			defer p.Close()
+			r, err := p.GetPriority(-100)
+			require.NoError(t, err)
+			require.NotNil(t, r)
+		})
+}
+
+func TestForEachIdle(t *testing.T) {
+	t.Parallel()
+
+	newPool := func() (*pool.Pool[*int], []*int) {
+		n := int64(0)
+		p := pool.New(
+			4,
+			100*time.Millisecond,
+			func() (*int, error) {
+				r := new(int)
+				*r = int(atomic.AddInt64(&n, 1))
+				return r, nil
+			},
+			func(r *int) {},
+			true,
+		)
+		rs, _ := p.GetN(4)
+		for _, r := range rs[:3] {
+			p.Put(r)
+		}
+		return p, rs
+	}
+
+	t.Run(
+		"When idle objects are inspected, every one is visited in order and stays idle",
+		func(t *testing.T) {
+			t.Parallel()
+			p, rs := newPool()
+			visited := []*int{}
+			p.ForEachIdle(func(r *int) bool {
+				visited = append(visited, r)
+				return true
+			})
+			require.Equal(t, rs[:3], visited, "Borrowed object is not visited")
+			require.Equal(t, int64(3), p.Idle())
+			require.Equal(t, int64(1), p.InUse())
+		})
+
+	t.Run(
+		"When fn returns false, iteration stops",
+		func(t *testing.T) {
+			t.Parallel()
+			p, _ := newPool()
+			calls := 0
+			p.ForEachIdle(func(r *int) bool {
+				calls++
+				return *r < 2
+			})
+			require.Equal(t, 2, calls)
+		})
+}
+
+func TestRemoveIdleWhere(t *testing.T) {
+	t.Parallel()
+
+	t.Run(
+		"When idle objects match predicate, only they are destructed and borrowed ones are left alone",
+		func(t *testing.T) {
+			t.Parallel()
+			n, destructed := int64(0), []int{}
+			m := sync.Mutex{}
+			p := pool.New(
+				4,
+				100*time.Millisecond,
+				func() (*int, error) {
+					r := new(int)
+					*r = int(atomic.AddInt64(&n, 1))
+					return r, nil
+				},
+				func(r *int) {
+					m.Lock()
+					destructed = append(destructed, *r)
+					m.Unlock()
+				},
+				true,
+			)
+			rs, _ := p.GetN(4)
+			for _, r := range rs[:3] {
+				p.Put(r)
+			}
+
+			removed := p.RemoveIdleWhere(func(r *int) bool { return *r%2 == 1 })
+			require.Equal(t, 2, removed)
+			require.ElementsMatch(t, []int{1, 3}, destructed)
+			require.Equal(t, int64(1), p.Idle())
+			require.Equal(t, int64(1), p.InUse())
+			require.Equal(t, int64(2), p.TotalDestroyed())
+
+			r, err := p.Get()
+			require.NoError(t, err)
+			require.Equal(t, 2, *r, "Remaining idle object is handed out")
+			require.True(t, p.Put(rs[3]), "Borrowed object is still accepted back")
+		})
+}
+
+func TestConvenienceConstructors(t *testing.T) {
+	t.Parallel()
+
+	t.Run(
+		"When pool is created with NewUnlimited, it has no capacity limit",
+		func(t *testing.T) {
+			t.Parallel()
+			p := pool.NewUnlimited(
+				func() (*int, error) { return new(int), nil },
+				func(r *int) {},
+			)
+			rs, err := p.GetN(100)
+			require.NoError(t, err)
+			require.Len(t, rs, 100)
+		})
+
+	t.Run(
+		"When pool is created with NewBounded, it is limited to capacity and options apply",
+		func(t *testing.T) {
+			t.Parallel()
+			p := pool.NewBounded(
+				2,
+				func() (*int, error) { return new(int), nil },
+				func(r *int) {},
+				pool.WithWait[*int](0),
+			)
+			_, err := p.GetN(2)
+			require.NoError(t, err)
+			_, err = p.Get()
+			require.ErrorIs(t, err, pool.ErrResourceUnavailable)
+		})
+}
+
+func TestStrictPut(t *testing.T) {
+	t.Parallel()
+
+	newPool := func() *pool.Pool[*int] {
+		return pool.New(
+			2,
+			10*time.Millisecond,
+			func() (*int, error) { return new(int), nil },
+			func(r *int) {},
+			false,
+			pool.WithStrictPut[*int](),
+		)
+	}
+
+	t.Run(
+		"When object is put back twice, the second Put is refused",
+		func(t *testing.T) {
+			t.Parallel()
+			p := newPool()
+			r, _ := p.Get()
+			require.True(t, p.Put(r))
+			require.ErrorIs(t, p.TryReturn(r), pool.ErrNotBorrowed)
+			require.Equal(t, int64(1), p.Idle())
+			require.Zero(t, p.InUse())
+		})
+
+	t.Run(
+		"When object pool never handed out is put, it is refused and not destructed",
+		func(t *testing.T) {
+			t.Parallel()
+			dstrCalls := int64(0)
+			p := pool.New(
+				2,
+				10*time.Millisecond,
+				func() (*int, error) { return new(int), nil },
+				func(r *int) { atomic.AddInt64(&dstrCalls, 1) },
+				false,
+				pool.WithStrictPut[*int](),
+			)
+			require.False(t, p.Put(new(int)))
+			require.Zero(t, p.PutN([]*int{new(int), new(int)}))
+			require.Zero(t, p.Idle())
+			require.Zero(t, dstrCalls)
+		})
+
+	t.Run(
+		"When borrowed object is put back, it is accepted",
+		func(t *testing.T) {
+			t.Parallel()
+			p := newPool()
+			rs, _ := p.GetN(2)
+			require.Equal(t, 2, p.PutN(rs))
+			require.Equal(t, int64(2), p.Idle())
+		})
+
+	t.Run(
+		"When object of another pool is put, refusal is logged as warning",
+		func(t *testing.T) {
+			t.Parallel()
+			var buf bytes.Buffer
+			logger := slog.New(slog.NewTextHandler(&buf, nil))
+			p := pool.New(
+				2,
+				10*time.Millisecond,
+				func() (*int, error) { return new(int), nil },
+				func(r *int) {},
+				false,
+				pool.WithStrictPut[*int](),
+				pool.WithLogger[*int](logger),
+			)
+			other := newPool()
+			r, _ := other.Get()
+
+			require.False(t, p.Put(r))
+			require.Contains(t, buf.String(), "level=WARN")
+			require.Contains(t, buf.String(), "not borrowed from this pool")
+			require.True(t, other.Put(r))
+		})
+}
+
+func TestGetOrCreate(t *testing.T) {
+	t.Parallel()
+
+	newPool := func(max int64) (*pool.Pool[*int], *int64) {
+		ctrCalls := int64(0)
+		return pool.New(
+			max,
+			50*time.Millisecond,
+			func() (*int, error) {
+				atomic.AddInt64(&ctrCalls, 1)
+				return new(int), nil
+			},
+			func(r *int) {},
+			false,
+		), &ctrCalls
+	}
+	create := func(v int) func() (*int, error) {
+		return func() (*int, error) { return &v, nil }
+	}
+
+	t.Run(
+		"When pool has no idle object, it is constructed with given function instead of pool's constructor",
+		func(t *testing.T) {
+			t.Parallel()
+			p, ctrCalls := newPool(2)
+			r, err := p.GetOrCreate(create(42))
+			require.NoError(t, err)
+			require.Equal(t, 42, *r)
+			require.Zero(t, *ctrCalls)
+			require.Equal(t, int64(1), p.InUse())
+
+			require.True(t, p.Put(r))
+			r2, err := p.GetOrCreate(create(7))
+			require.NoError(t, err)
+			require.Same(t, r, r2, "Idle object is handed out first")
+		})
+
+	t.Run(
+		"When pool is full, GetOrCreate waits for capacity and respects max",
+		func(t *testing.T) {
+			t.Parallel()
+			p, _ := newPool(1)
+			r, _ := p.Get()
+			_, err := p.GetOrCreate(create(1))
+			require.ErrorIs(t, err, pool.ErrResourceUnavailable)
+			require.Equal(t, int64(1), p.InUse())
+
+			got := make(chan *int)
+			go func() {
+				r, _ := p.GetOrCreate(create(5))
+				got <- r
+			}()
+			require.Eventually(t, func() bool {
+				return p.Stats().Waiting == 1
+			}, time.Second, time.Millisecond)
+			p.Discard(r)
+			require.Equal(t, 5, *<-got, "Freed capacity is used to call given function")
+			require.Equal(t, int64(1), p.InUse())
+		})
+
+	t.Run(
+		"When given function fails, capacity is given back",
+		func(t *testing.T) {
+			t.Parallel()
+			p, _ := newPool(1)
+			errCreate := errors.New("create failed")
+			_, err := p.GetOrCreate(func() (*int, error) { return nil, errCreate })
+			require.ErrorIs(t, err, errCreate)
+			require.Zero(t, p.InUse())
+			_, err = p.Get()
+			require.NoError(t, err)
+		})
+
+	t.Run(
+		"When given function panics, panic is returned as error and capacity is given back",
+		func(t *testing.T) {
+			t.Parallel()
+			p, _ := newPool(1)
+			_, err := p.GetOrCreate(func() (*int, error) { panic("create failed") })
+			require.ErrorIs(t, err, pool.ErrPanicked)
+			require.Zero(t, p.InUse())
+			r, err := p.GetOrCreate(create(3))
+			require.NoError(t, err)
+			require.Equal(t, 3, *r)
+		})
+}
+
+func TestIsFull(t *testing.T) {
+	t.Parallel()
+
+	t.Run(
+		"When every object is borrowed, pool is full until one is returned",
+		func(t *testing.T) {
+			t.Parallel()
+			p := pool.New(
+				2,
+				10*time.Millisecond,
+				func() (*int, error) { return new(int), nil },
+				func(r *int) {},
+				false,
+			)
+			require.False(t, p.IsFull())
+			rs, _ := p.GetN(2)
+			require.True(t, p.IsFull())
+			p.Put(rs[0])
+			require.False(t, p.IsFull(), "Idle object can be handed out")
+		})
+
+	t.Run(
+		"When pool is unlimited, it is never full",
+		func(t *testing.T) {
+			t.Parallel()
+			p := pool.New(
+				-1,
+				10*time.Millisecond,
+				func() (*int, error) { return new(int), nil },
+				func(r *int) {},
+				false,
+			)
+			_, _ = p.GetN(10)
+			require.False(t, p.IsFull())
+		})
+}
+
+func TestCleanupTimeout(t *testing.T) {
+	t.Parallel()
+
+	newPool := func(n int, dstr func(*int) error) *pool.Pool[*int] {
+		p := pool.NewWithOptions(
+			func() (*int, error) { return new(int), nil },
+			nil,
+			pool.WithDestructorErr(dstr),
+		)
+		rs, _ := p.GetN(n)
+		p.PutN(rs)
+		return p
+	}
+
+	t.Run(
+		"When destructors are slow, they run concurrently",
+		func(t *testing.T) {
+			t.Parallel()
+			dstrCalls := int64(0)
+			p := newPool(32, func(r *int) error {
+				time.Sleep(20 * time.Millisecond)
+				atomic.AddInt64(&dstrCalls, 1)
+				return nil
+			})
+			start := time.Now()
+			pending, err := p.CleanupTimeout(context.Background())
+			require.NoError(t, err)
+			require.Zero(t, pending)
+			require.Equal(t, int64(32), dstrCalls)
+			require.Less(t, time.Since(start), 32*20*time.Millisecond/4)
+
+			_, err = p.Get()
+			require.ErrorIs(t, err, pool.ErrPoolClosed)
+		})
+
+	t.Run(
+		"When context is done first, number of unfinished destructions is reported",
+		func(t *testing.T) {
+			t.Parallel()
+			release := make(chan struct{})
+			defer close(release)
+			p := newPool(20, func(r *int) error {
+				<-release
+				return nil
+			})
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+			pending, err := p.CleanupTimeout(ctx)
+			require.ErrorIs(t, err, context.DeadlineExceeded)
+			require.Equal(t, 20, pending)
+		})
+
+	t.Run(
+		"When destructors fail, errors are joined",
+		func(t *testing.T) {
+			t.Parallel()
+			errDstr := errors.New("destructor failed")
+			p := newPool(3, func(r *int) error { return errDstr })
+			pending, err := p.CleanupTimeout(context.Background())
+			require.ErrorIs(t, err, errDstr)
+			require.Zero(t, pending)
+
+			pending, err = p.CleanupTimeout(context.Background())
+			require.NoError(t, err, "Closing closed pool does nothing")
+			require.Zero(t, pending)
+		})
+}
+
+func TestDepartedWaiters(t *testing.T) {
+	t.Parallel()
+
+	t.Run(
+		"When waiters time out while objects are handed over to them, no object is lost",
+		func(t *testing.T) {
+			t.Parallel()
+			p := pool.New(
+				4,
+				time.Millisecond,
+				func() (*int, error) { return new(int), nil },
+				func(r *int) {},
+				false,
+			)
+
+			done := make(chan struct{})
+			for i := 0; i < 32; i++ {
+				go func(i int) {
+					defer func() { done <- struct{}{} }()
+					for j := 0; j < 200; j++ {
+						r, err := p.GetTimeout(time.Duration(j%3) * 100 * time.Microsecond)
+						if err != nil {
+							continue
+						}
+						if i%2 == 0 {
+							time.Sleep(50 * time.Microsecond)
+						}
+						p.Put(r)
+					}
+				}(i)
+			}
+			for i := 0; i < 32; i++ {
+				<-done
+			}
+
+			require.Eventually(t, func() bool {
+				return p.InUse() == 0
+			}, time.Second, time.Millisecond, "Objects handed over to departed waiters are put back")
+			s := p.Stats()
+			require.Zero(t, s.Waiting)
+			require.Equal(t, s.TotalCreated-s.TotalDestroyed, s.Idle)
+			require.LessOrEqual(t, s.Idle, int64(4))
+
+			rs, err := p.GetN(4)
+			require.NoError(t, err, "Full capacity is still available")
+			require.Len(t, rs, 4)
+		})
+}
+
+func TestCancelledWaiters(t *testing.T) {
+	t.Run(
+		"When many waiters are cancelled mid-flight, they leave the queue and neither objects nor GRs leak",
+		func(t *testing.T) {
+			goroutines := runtime.NumGoroutine()
+			p := pool.New(
+				2,
+				time.Minute,
+				func() (*int, error) {
+					time.Sleep(time.Millisecond)
+					return new(int), nil
+				},
+				func(r *int) {},
+				false,
+			)
+			rs, _ := p.GetN(2)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			errs := make(chan error)
+			for i := 0; i < 50; i++ {
+				go func() {
+					r, err := p.GetContext(ctx)
+					if err == nil {
+						p.Put(r)
+					}
+					errs <- err
+				}()
+			}
+			require.Eventually(t, func() bool {
+				return p.Stats().Waiting == 50
+			}, time.Second, time.Millisecond)
+
+			p.Discard(rs[0]) // constructs object for the first waiter
+			cancel()
+			for i := 0; i < 50; i++ {
+				<-errs
+			}
+			require.Zero(t, p.Stats().Waiting, "Cancelled waiters leave the queue")
+
+			p.Put(rs[1])
+			require.Eventually(t, func() bool {
+				return p.InUse() == 0
+			}, time.Second, time.Millisecond, "Object dispatched to cancelled waiter is recovered")
+			got, err := p.GetN(2)
+			require.NoError(t, err, "Full capacity is still available")
+			p.PutN(got)
+
+			require.NoError(t, p.Close())
+			require.Eventually(t, func() bool {
+				// Eventually checks condition in a GR of its own.
+				return runtime.NumGoroutine() <= goroutines+1
+			}, time.Second, time.Millisecond, "No GR is left behind")
+		})
+}
+
+func TestPutWait(t *testing.T) {
+	t.Parallel()
+
+	newFullPool := func() (*pool.Pool[*int], []*int) {
+		p := pool.New(
+			2,
+			10*time.Millisecond,
+			func() (*int, error) { return new(int), nil },
+			func(r *int) {},
+			false,
+		)
+		rs, _ := p.GetN(2)
+		return p, rs
+	}
+
+	t.Run(
+		"When pool has no room, PutWait waits until capacity frees up",
+		func(t *testing.T) {
+			t.Parallel()
+			p, rs := newFullPool()
+			outsider := new(int)
+
+			errs := make(chan error)
+			go func() {
+				errs <- p.PutWait(context.Background(), outsider)
+			}()
+			select {
+			case <-errs:
+				t.Fatal("PutWait returned while pool is full")
+			case <-time.After(20 * time.Millisecond):
+			}
+
+			p.Discard(rs[0])
+			require.NoError(t, <-errs)
+			require.Equal(t, int64(1), p.Idle())
+
+			r, err := p.Get()
+			require.NoError(t, err)
+			require.Same(t, outsider, r)
+		})
+
+	t.Run(
+		"When capacity grows, waiting PutWait stores object",
+		func(t *testing.T) {
+			t.Parallel()
+			p, _ := newFullPool()
+			errs := make(chan error)
+			go func() {
+				errs <- p.PutWait(context.Background(), new(int))
+			}()
+			time.Sleep(5 * time.Millisecond)
+			p.SetMax(3)
+			require.NoError(t, <-errs)
+			require.Equal(t, int64(1), p.Idle())
+		})
+
+	t.Run(
+		"When context is done first, PutWait gives up and caller keeps object",
+		func(t *testing.T) {
+			t.Parallel()
+			p, _ := newFullPool()
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+			require.ErrorIs(t, p.PutWait(ctx, new(int)), context.DeadlineExceeded)
+			require.Zero(t, p.Idle())
+		})
+
+	t.Run(
+		"When pool is closed while PutWait waits, object is destructed",
+		func(t *testing.T) {
+			t.Parallel()
+			p, _ := newFullPool()
+			errs := make(chan error)
+			go func() {
+				errs <- p.PutWait(context.Background(), new(int))
+			}()
+			time.Sleep(5 * time.Millisecond)
+			p.Close()
+			require.ErrorIs(t, <-errs, pool.ErrPoolClosed)
+		})
+}
+
+func TestRequestReuse(t *testing.T) {
+	t.Parallel()
+
+	t.Run(
+		"When waiter leaves while object is constructed for it, next waiter gets object exactly once",
+		func(t *testing.T) {
+			t.Parallel()
+			slow := atomic.Bool{}
+			p := pool.New(
+				1,
+				time.Second,
+				func() (*int, error) {
+					if slow.Load() {
+						time.Sleep(30 * time.Millisecond)
+					}
+					return new(int), nil
+				},
+				func(r *int) {},
+				false,
+			)
+			r, _ := p.Get()
+			slow.Store(true)
+
+			left := make(chan error)
+			go func() {
+				_, err := p.GetTimeout(10 * time.Millisecond)
+				left <- err
+			}()
+			require.Eventually(t, func() bool {
+				return p.Stats().Waiting == 1
+			}, time.Second, time.Millisecond)
+			p.Discard(r) // construction for the waiter starts
+			require.ErrorIs(t, <-left, pool.ErrResourceUnavailable)
+
+			fresh, err := p.Get()
+			require.NoError(t, err)
+			require.Equal(t, int64(1), p.InUse())
+			_, err = p.GetTimeout(50 * time.Millisecond)
+			require.ErrorIs(t, err, pool.ErrResourceUnavailable, "Object is not handed out twice")
+			require.True(t, p.Put(fresh))
+			require.Equal(t, int64(1), p.Idle())
+		})
+}
+
+func TestWeight(t *testing.T) {
+	t.Parallel()
+
+	type conn struct{ weight int64 }
+	newPool := func(weights ...int64) *pool.Pool[*conn] {
+		next := int64(0)
+		return pool.New(
+			10,
+			10*time.Millisecond,
+			func() (*conn, error) {
+				i := atomic.AddInt64(&next, 1) - 1
+				return &conn{weight: weights[i%int64(len(weights))]}, nil
+			},
+			func(c *conn) {},
+			false,
+			pool.WithWeight(func(c *conn) int64 { return c.weight }),
+		)
+	}
+
+	t.Run(
+		"When objects are heavy, capacity is a budget of their total weight",
+		func(t *testing.T) {
+			t.Parallel()
+			p := newPool(4)
+			rs, err := p.GetN(2)
+			require.NoError(t, err)
+			require.True(t, p.IsFull(), "Third object of weight 4 would take pool over capacity of 10")
+			_, err = p.Get()
+			require.ErrorIs(t, err, pool.ErrResourceUnavailable)
+
+			p.Discard(rs[0])
+			require.False(t, p.IsFull())
+			require.Equal(t, int64(1), p.InUse())
+		})
+
+	t.Run(
+		"When object turns out heavier than expected, it is destructed instead of taking pool over capacity",
+		func(t *testing.T) {
+			t.Parallel()
+			p := newPool(3, 3, 1, 5)
+			rs, err := p.GetN(3)
+			require.NoError(t, err)
+			require.False(t, p.IsFull())
+
+			_, err = p.Get()
+			require.ErrorIs(t, err, pool.ErrResourceUnavailable, "Object of weight 5 doesn't fit next to weight of 7")
+			require.Equal(t, int64(1), p.TotalDestroyed())
+			require.Equal(t, int64(3), p.InUse())
+			require.True(t, p.IsFull(), "Next object is expected to be as heavy")
+
+			p.Discard(rs[0])
+			r, err := p.Get()
+			require.NoError(t, err)
+			require.Equal(t, int64(3), r.weight)
+		})
+
+	t.Run(
+		"When objects fill capacity unevenly, returned ones are kept rather than destructed",
+		func(t *testing.T) {
+			t.Parallel()
+			p := newPool(3)
+			for i := 0; i < 10; i++ {
+				rs, err := p.GetN(3)
+				require.NoError(t, err)
+				require.Equal(t, 3, p.PutN(rs))
+			}
+			require.Zero(t, p.TotalDestroyed())
+			require.Equal(t, int64(3), p.TotalCreated())
+		})
+
+	t.Run(
+		"When object is brought from outside, it is accepted only if its weight fits",
+		func(t *testing.T) {
+			t.Parallel()
+			p := newPool(3)
+			_, err := p.GetN(2)
+			require.NoError(t, err)
+			require.ErrorIs(t, p.TryReturn(&conn{weight: 5}), pool.ErrPoolFull)
+			require.NoError(t, p.TryReturn(&conn{weight: 4}))
+			require.False(t, p.IsFull(), "Idle object can be handed out")
+			require.InDelta(t, 0.6, p.Utilization(), 1e-9)
+		})
+
+	t.Run(
+		"When objects are returned, their weight stays counted until they are destructed",
+		func(t *testing.T) {
+			t.Parallel()
+			p := newPool(5)
+			rs, err := p.GetN(2)
+			require.NoError(t, err)
+			require.Equal(t, 2, p.PutN(rs))
+			require.Equal(t, int64(2), p.Idle())
+			require.ErrorIs(t, p.TryReturn(&conn{weight: 1}), pool.ErrPoolFull)
+
+			require.NoError(t, p.ResetIdle())
+			require.NoError(t, p.TryReturn(&conn{weight: 10}))
+		})
+}
+
+func TestGetWithInfo(t *testing.T) {
+	t.Parallel()
+
+	t.Run(
+		"When object is reused, its age, use count and last return are reported",
+		func(t *testing.T) {
+			t.Parallel()
+			clock := newFakeClock()
+			p := pool.NewWithOptions(
+				func() (*int, error) { return new(int), nil },
+				func(r *int) {},
+				pool.WithClock[*int](clock),
+			)
+			r, info, err := p.GetWithInfo()
+			require.NoError(t, err)
+			require.Equal(t, pool.ResourceInfo{
+				Fresh:     true,
+				CreatedAt: time.Unix(0, 0),
+				Uses:      1,
+			}, info)
+
+			clock.Advance(time.Minute)
+			require.True(t, p.Put(r))
+			clock.Advance(time.Second)
+
+			r2, info, err := p.GetWithInfo()
+			require.NoError(t, err)
+			require.Same(t, r, r2)
+			require.Equal(t, pool.ResourceInfo{
+				CreatedAt:  time.Unix(0, 0),
+				Age:        time.Minute + time.Second,
+				ReturnedAt: time.Unix(0, 0).Add(time.Minute),
+				Uses:       2,
+			}, info)
+		})
+
+	t.Run(
+		"When object type is not comparable, only freshness is reported",
+		func(t *testing.T) {
+			t.Parallel()
+			p := pool.NewWithOptions(
+				func() ([]int, error) { return []int{1}, nil },
+				func(r []int) {},
+			)
+			_, info, err := p.GetWithInfo()
+			require.NoError(t, err)
+			require.Equal(t, pool.ResourceInfo{Fresh: true}, info)
+		})
+}
+
+func TestMustGet(t *testing.T) {
+	t.Parallel()
+
+	t.Run(
+		"When object is available, MustGet returns it",
+		func(t *testing.T) {
+			t.Parallel()
+			p := pool.New(
+				1,
+				time.Millisecond,
+				func() (*int, error) { return new(int), nil },
+				func(r *int) {},
+				false,
+			)
+			require.NotNil(t, p.MustGet())
+		})
+
+	t.Run(
+		"When Get fails, MustGet panics",
+		func(t *testing.T) {
+			t.Parallel()
+			p := pool.New(
+				1,
+				time.Millisecond,
+				func() (*int, error) { return nil, errors.New("no connection") },
+				func(r *int) {},
+				false,
+			)
+			require.PanicsWithValue(t, "pool: MustGet: no connection", func() { p.MustGet() })
+		})
+}
+
+func TestShrinkTo(t *testing.T) {
+	t.Parallel()
+
+	t.Run(
+		"When idle objects exceed target, least recently returned ones are destructed",
+		func(t *testing.T) {
+			t.Parallel()
+			dstrCalls := int64(0)
+			p := pool.New(
+				5,
+				10*time.Millisecond,
+				func() (*int, error) { return new(int), nil },
+				func(r *int) { atomic.AddInt64(&dstrCalls, 1) },
+				false,
+			)
+			rs, _ := p.GetN(5)
+			for _, r := range rs[:4] {
+				p.Put(r)
+			}
+
+			require.Equal(t, 3, p.ShrinkTo(1))
+			require.Equal(t, int64(3), dstrCalls)
+			require.Equal(t, int64(1), p.Idle())
+			require.Equal(t, int64(1), p.InUse(), "Borrowed object is left alone")
+			r, _ := p.Get()
+			require.Same(t, rs[3], r, "Most recently returned object stays")
+
+			require.Zero(t, p.ShrinkTo(1), "Nothing to shrink")
+			_, err := p.GetN(3)
+			require.NoError(t, err, "Capacity is freed")
+		})
+
+	t.Run(
+		"When target is negative, every idle object is destructed",
+		func(t *testing.T) {
+			t.Parallel()
+			p := pool.New(
+				5,
+				10*time.Millisecond,
+				func() (*int, error) { return new(int), nil },
+				func(r *int) {},
+				false,
+			)
+			rs, _ := p.GetN(2)
+			p.PutN(rs)
+
+			require.Equal(t, 2, p.ShrinkTo(-1))
+			require.Zero(t, p.Idle())
+			require.Zero(t, p.InUse())
+		})
+}
+
+func TestKeepalive(t *testing.T) {
+	t.Parallel()
+
+	t.Run(
+		"When idle objects are probed, dead ones are destructed and live ones stay idle",
+		func(t *testing.T) {
+			t.Parallel()
+			clock := newFakeClock()
+			probes, dstrCalls := int64(0), int64(0)
+			p := pool.NewWithOptions(
+				func() (*int, error) { return new(int), nil },
+				func(r *int) { atomic.AddInt64(&dstrCalls, 1) },
+				pool.WithClock[*int](clock),
+				pool.WithKeepalive(time.Minute, func(r *int) error {
+					atomic.AddInt64(&probes, 1)
+					if *r < 0 {
+						return errors.New("connection dropped")
+					}
+					return nil
+				}),
+			)
+			defer p.Close()
+			rs, _ := p.GetN(3)
+			*rs[1] = -1
+			p.PutN(rs)
+
+			require.Eventually(t, func() bool { return clock.Pending() == 1 }, time.Second, time.Millisecond)
+			clock.Advance(time.Minute)
+			require.Eventually(t, func() bool {
+				return atomic.LoadInt64(&dstrCalls) == 1 && p.Idle() == 2
+			}, time.Second, time.Millisecond)
+			require.Equal(t, int64(3), atomic.LoadInt64(&probes))
+			require.Zero(t, p.InUse())
+
+			require.Eventually(t, func() bool { return clock.Pending() == 1 }, time.Second, time.Millisecond)
+			clock.Advance(time.Minute)
+			require.Eventually(t, func() bool {
+				return atomic.LoadInt64(&probes) == 5
+			}, time.Second, time.Millisecond, "Every idle object is probed each interval")
+		})
+
+	t.Run(
+		"When keepalive interval is not positive, idle objects are probed every 30 seconds instead of nonstop",
+		func(t *testing.T) {
+			t.Parallel()
+			clock := newFakeClock()
+			probes := int64(0)
+			p := pool.NewWithOptions(
+				func() (*int, error) { return new(int), nil },
+				func(r *int) {},
+				pool.WithClock[*int](clock),
+				pool.WithKeepalive(0, func(r *int) error {
+					atomic.AddInt64(&probes, 1)
+					return nil
+				}),
+			)
+			defer p.Close()
+			r, _ := p.Get()
+			p.Put(r)
+
+			require.Eventually(t, func() bool { return clock.Pending() == 1 }, time.Second, time.Millisecond)
+			clock.Advance(29 * time.Second)
+			require.Zero(t, atomic.LoadInt64(&probes))
+			clock.Advance(time.Second)
+			require.Eventually(t, func() bool {
+				return atomic.LoadInt64(&probes) == 1
+			}, time.Second, time.Millisecond)
+		})
+
+	t.Run(
+		"When pool is closed, probing stops",
+		func(t *testing.T) {
+			t.Parallel()
+			clock := newFakeClock()
+			probes := int64(0)
+			p := pool.NewWithOptions(
+				func() (*int, error) { return new(int), nil },
+				func(r *int) {},
+				pool.WithClock[*int](clock),
+				pool.WithKeepalive(time.Minute, func(r *int) error {
+					atomic.AddInt64(&probes, 1)
+					return nil
+				}),
+			)
+			r, _ := p.Get()
+			p.Put(r)
+			require.Eventually(t, func() bool { return clock.Pending() == 1 }, time.Second, time.Millisecond)
+			p.Close()
+			clock.Advance(time.Minute)
+			time.Sleep(10 * time.Millisecond)
+			require.Zero(t, atomic.LoadInt64(&probes))
+		})
+}
+
+func TestOverflow(t *testing.T) {
+	t.Parallel()
+
+	t.Run(
+		"When pool stays full, Get hands out overflow object, which is destructed once put back",
+		func(t *testing.T) {
+			t.Parallel()
+			dstrCalls := int64(0)
+			overflow := new(int)
+			p := pool.New(
+				1,
+				10*time.Millisecond,
+				func() (*int, error) {
+					return new(int), nil
+				},
+				func(r *int) { atomic.AddInt64(&dstrCalls, 1) },
+				true,
+				pool.WithOverflow(func() (*int, error) {
+					return overflow, nil
+				}),
+			)
+			pooled, _ := p.Get()
+
+			r, err := p.Get()
+			require.NoError(t, err)
+			require.Same(t, overflow, r)
+			require.Equal(t, int64(1), p.InUse(), "Overflow object doesn't count towards capacity")
+			require.Equal(t, int64(1), p.Stats().Overflow)
+
+			require.ErrorIs(t, p.TryReturn(r), pool.ErrResourceDestroyed)
+			require.Equal(t, int64(1), dstrCalls)
+			require.Zero(t, p.Idle())
+			require.Zero(t, p.Stats().Overflow)
+
+			require.True(t, p.Put(pooled))
+			require.Equal(t, int64(1), p.Idle())
+		})
+
+	t.Run(
+		"When overflow CTR fails, Get returns its error",
+		func(t *testing.T) {
+			t.Parallel()
+			ctrErr := errors.New("backend is down")
+			p := pool.New(
+				1,
+				0,
+				func() (*int, error) {
+					return new(int), nil
+				},
+				func(r *int) {},
+				true,
+				pool.WithOverflow(func() (*int, error) {
+					return nil, ctrErr
+				}),
+			)
+			_, _ = p.Get()
+
+			_, err := p.Get()
+			require.ErrorIs(t, err, ctrErr)
+			require.Zero(t, p.Stats().Overflow)
+		})
+}
+
+func TestCreateTimeout(t *testing.T) {
+	t.Parallel()
+
+	t.Run(
+		"When CTR hangs, Get fails with ErrCreateTimeout and late object is destructed",
+		func(t *testing.T) {
+			t.Parallel()
+			release := make(chan struct{})
+			dstrCalls := int64(0)
+			p := pool.New(
+				1,
+				time.Minute,
+				func() (*int, error) {
+					<-release
+					return new(int), nil
+				},
+				func(r *int) { atomic.AddInt64(&dstrCalls, 1) },
+				true,
+				pool.WithCreateTimeout[*int](10*time.Millisecond),
+			)
+
+			start := time.Now()
+			_, err := p.Get()
+			require.ErrorIs(t, err, pool.ErrCreateTimeout)
+			require.Less(t, time.Since(start), time.Minute)
+			require.Zero(t, p.InUse(), "Capacity is freed")
+
+			close(release)
+			require.Eventually(t, func() bool {
+				return atomic.LoadInt64(&dstrCalls) == 1
+			}, time.Second, time.Millisecond)
+			require.Equal(t, int64(1), p.TotalCreated(), "Late object is counted like any other")
+			require.Equal(t, int64(1), p.TotalDestroyed())
+		})
+
+	t.Run(
+		"When CTR finishes in time, object is handed out",
+		func(t *testing.T) {
+			t.Parallel()
+			p := pool.NewWithOptions(
+				nil,
+				func(r *int) {},
+				pool.WithCreateTimeout[*int](time.Minute),
+				pool.WithContextFactory(func(ctx context.Context) (*int, error) {
+					_, ok := ctx.Deadline()
+					require.True(t, ok)
+					return new(int), nil
+				}),
+			)
+			r, err := p.Get()
+			require.NoError(t, err)
+			require.NotNil(t, r)
+		})
+}
+
+func TestFastPath(t *testing.T) {
+	t.Parallel()
+
+	t.Run(
+		"When many callers take idle objects concurrently, no object is handed out twice or lost",
+		func(t *testing.T) {
+			t.Parallel()
+			p := pool.New(
+				8,
+				time.Second,
+				func() (*int, error) { return new(int), nil },
+				func(r *int) {},
+				true,
+			)
+
+			var borrowed sync.Map
+			errs := make(chan error, 32)
+			for i := 0; i < 32; i++ {
+				go func() {
+					for j := 0; j < 500; j++ {
+						r, err := p.Get()
+						if err != nil {
+							errs <- err
+							return
+						}
+						if _, dup := borrowed.LoadOrStore(r, struct{}{}); dup {
+							errs <- errors.New("object is handed out twice")
+							return
+						}
+						borrowed.Delete(r)
+						p.Put(r)
+					}
+					errs <- nil
+				}()
+			}
+			for i := 0; i < 32; i++ {
+				require.NoError(t, <-errs)
+			}
+
+			require.Zero(t, p.InUse())
+			require.LessOrEqual(t, p.TotalCreated(), int64(8))
+			require.Equal(t, p.TotalCreated(), p.Idle(), "No object is lost")
+		})
+}
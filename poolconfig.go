@@ -0,0 +1,63 @@
+package pool
+
+import (
+	"time"
+)
+
+// PoolConfig is a read-only view of effective pool settings, see Config. Zero
+// and (-1) values mean the same as for the options that set them.
+type PoolConfig struct {
+	// Capacity, (-1) for unlimited pool. Reflects SetMax.
+	Max int64
+	// How long Get waits for resource of full pool, see WithWait.
+	Wait        time.Duration
+	Preallocate bool
+	Warmup      int
+
+	MaxIdle     int64
+	MinIdle     int64
+	MaxLifetime time.Duration
+	MaxIdleTime time.Duration
+	MaxUses     int
+
+	RetryAttempts        int
+	RetryBackoff         time.Duration
+	CreateTimeout        time.Duration
+	MaxConcurrentCreates int
+	CreateRateLimit      float64
+
+	StrictPut         bool
+	SweepInterval     time.Duration
+	KeepaliveInterval time.Duration
+}
+
+// Config returns effective settings of the pool, e.g. to describe it in logs
+// or health endpoints.
+func (pool *Pool[T]) Config() PoolConfig {
+	pool.m.Lock()
+	max := pool.max
+	pool.m.Unlock()
+
+	c := PoolConfig{
+		Max:                  max,
+		Wait:                 pool.waitTime(),
+		Preallocate:          pool.preallocate,
+		Warmup:               pool.warmup,
+		MaxIdle:              pool.maxIdle,
+		MinIdle:              pool.minIdle,
+		MaxLifetime:          pool.maxLifetime,
+		MaxIdleTime:          pool.maxIdleTime,
+		MaxUses:              pool.maxUses,
+		RetryAttempts:        pool.retryAttempts,
+		RetryBackoff:         pool.retryBackoff,
+		CreateTimeout:        pool.createTimeout,
+		MaxConcurrentCreates: pool.maxConcurrentCreates,
+		CreateRateLimit:      pool.createRateLimit,
+		StrictPut:            pool.strictPut,
+		SweepInterval:        pool.sweepInterval,
+	}
+	if pool.keepaliveFn != nil {
+		c.KeepaliveInterval = pool.keepaliveInterval
+	}
+	return c
+}
@@ -0,0 +1,58 @@
+package pool_test
+
+import (
+	"testing"
+	"time"
+
+	pool "github.com/posidoni/resource-pool"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig(t *testing.T) {
+	t.Parallel()
+
+	t.Run(
+		"When pool is configured with options, Config reports effective settings",
+		func(t *testing.T) {
+			t.Parallel()
+			p := pool.New(
+				3,
+				time.Second,
+				func() (*int, error) { return new(int), nil },
+				func(r *int) {},
+				true,
+				pool.WithMaxIdle[*int](2),
+				pool.WithMaxLifetime[*int](time.Hour),
+				pool.WithCreateTimeout[*int](time.Minute),
+				pool.WithStrictPut[*int](),
+			)
+			defer p.Close()
+
+			require.Equal(t, pool.PoolConfig{
+				Max:           3,
+				Wait:          time.Second,
+				Preallocate:   true,
+				MaxIdle:       2,
+				MaxLifetime:   time.Hour,
+				RetryAttempts: 1,
+				CreateTimeout: time.Minute,
+				StrictPut:     true,
+				SweepInterval: time.Second,
+			}, p.Config())
+		})
+
+	t.Run(
+		"When capacity is changed, Config reflects it",
+		func(t *testing.T) {
+			t.Parallel()
+			p := pool.NewUnlimited(
+				func() (*int, error) { return new(int), nil },
+				func(r *int) {},
+			)
+			require.Equal(t, int64(-1), p.Config().Max)
+			require.Equal(t, int64(-1), p.Config().MaxIdle)
+
+			p.SetMax(5)
+			require.Equal(t, int64(5), p.Config().Max)
+		})
+}
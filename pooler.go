@@ -0,0 +1,14 @@
+package pool
+
+// Pooler is the core of pool API, so that consumers can depend on it instead
+// of concrete pool and substitute fakes in tests.
+type Pooler[T any] interface {
+	Get() (T, error)
+	Put(resource T) bool
+	Cleanup() error
+}
+
+var (
+	_ Pooler[int] = (*Pool[int])(nil)
+	_ Pooler[int] = (*Sharded[int])(nil)
+)
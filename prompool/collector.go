@@ -0,0 +1,69 @@
+// Package prompool exports pool metrics to Prometheus.
+//
+// It lives in its own package, so that pool itself doesn't depend on
+// Prometheus client.
+package prompool
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	pool "github.com/posidoni/resource-pool"
+)
+
+// StatsSource is anything that reports pool stats, e.g. *pool.Pool.
+type StatsSource interface {
+	Stats() pool.Stats
+}
+
+// Collector is prometheus.Collector reporting metrics sourced from pool
+// Stats.
+type Collector struct {
+	source StatsSource
+
+	inUse     *prometheus.Desc
+	idle      *prometheus.Desc
+	created   *prometheus.Desc
+	destroyed *prometheus.Desc
+	timeouts  *prometheus.Desc
+}
+
+// NewCollector returns Collector for the given pool. Metric names are
+// prefixed with "pool_"; name is reported as "pool" label to distinguish
+// several pools registered with the same registry.
+//
+//	prometheus.MustRegister(prompool.NewCollector("db", p))
+func NewCollector(name string, source StatsSource) *Collector {
+	labels := prometheus.Labels{"pool": name}
+	desc := func(metric, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName("pool", "", metric), help, nil, labels)
+	}
+
+	return &Collector{
+		source:    source,
+		inUse:     desc("in_use", "Number of resources currently borrowed from the pool."),
+		idle:      desc("idle", "Number of resources currently stored in the pool."),
+		created:   desc("created_total", "Number of resources constructed by the pool."),
+		destroyed: desc("destroyed_total", "Number of resources destructed by the pool."),
+		timeouts:  desc("timeouts_total", "Number of Get calls that gave up waiting for resource."),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.inUse
+	ch <- c.idle
+	ch <- c.created
+	ch <- c.destroyed
+	ch <- c.timeouts
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	s := c.source.Stats()
+
+	ch <- prometheus.MustNewConstMetric(c.inUse, prometheus.GaugeValue, float64(s.InUse))
+	ch <- prometheus.MustNewConstMetric(c.idle, prometheus.GaugeValue, float64(s.Idle))
+	ch <- prometheus.MustNewConstMetric(c.created, prometheus.CounterValue, float64(s.TotalCreated))
+	ch <- prometheus.MustNewConstMetric(c.destroyed, prometheus.CounterValue, float64(s.TotalDestroyed))
+	ch <- prometheus.MustNewConstMetric(c.timeouts, prometheus.CounterValue, float64(s.TimedOut.Count))
+}
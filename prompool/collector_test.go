@@ -0,0 +1,55 @@
+package prompool_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	pool "github.com/posidoni/resource-pool"
+	"github.com/posidoni/resource-pool/prompool"
+)
+
+func TestCollector(t *testing.T) {
+	t.Parallel()
+
+	t.Run(
+		"When pool is collected, collector reports its stats",
+		func(t *testing.T) {
+			t.Parallel()
+			p := pool.New(
+				2,
+				time.Millisecond,
+				func() (int, error) {
+					return 0, nil
+				},
+				func(r int) {},
+				true,
+			)
+			_, _ = p.Get()
+			_, _ = p.Get()
+			_, _ = p.Get() // times out
+
+			expected := `
+# HELP pool_created_total Number of resources constructed by the pool.
+# TYPE pool_created_total counter
+pool_created_total{pool="test"} 2
+# HELP pool_destroyed_total Number of resources destructed by the pool.
+# TYPE pool_destroyed_total counter
+pool_destroyed_total{pool="test"} 0
+# HELP pool_idle Number of resources currently stored in the pool.
+# TYPE pool_idle gauge
+pool_idle{pool="test"} 0
+# HELP pool_in_use Number of resources currently borrowed from the pool.
+# TYPE pool_in_use gauge
+pool_in_use{pool="test"} 2
+# HELP pool_timeouts_total Number of Get calls that gave up waiting for resource.
+# TYPE pool_timeouts_total counter
+pool_timeouts_total{pool="test"} 1
+`
+			c := prompool.NewCollector("test", p)
+			require.NoError(t, testutil.CollectAndCompare(c, strings.NewReader(expected)))
+		})
+}
@@ -0,0 +1,54 @@
+package pool
+
+import (
+	"sync"
+	"time"
+)
+
+// Token bucket limiting rate of constructor calls, see WithCreateRateLimit.
+type limiter struct {
+	m sync.Mutex
+	// Tokens added per second.
+	rate float64
+	// Max number of tokens bucket holds.
+	burst float64
+	// Tokens available at last, negative when they are owed to waiting callers.
+	tokens float64
+	last   time.Time
+}
+
+func newLimiter(rate float64, now time.Time) *limiter {
+	burst := rate
+	if burst < 1 {
+		burst = 1
+	}
+	return &limiter{rate: rate, burst: burst, tokens: burst, last: now}
+}
+
+// Takes token and reports how long caller has to wait before it may use it.
+func (l *limiter) reserve(now time.Time) time.Duration {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	if elapsed := now.Sub(l.last); elapsed > 0 {
+		l.tokens += elapsed.Seconds() * l.rate
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+		l.last = now
+	}
+
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// Gives back token of caller that gave up waiting.
+func (l *limiter) cancel() {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	l.tokens++
+}
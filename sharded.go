@@ -0,0 +1,148 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// Sharded spreads resources over several independent pools, so that callers
+// borrowing and returning resources concurrently contend for different
+// locks. It pays off for large pools under heavy concurrency, at the cost of
+// weaker guarantees: waiters are served in arrival order per shard only and
+// caller may wait on one shard while another has idle resource returned.
+// Borrowed resources are told apart by value to find their shard, so they
+// must be distinct, e.g. pointers: equal resources of different shards would
+// be mistaken for one another.
+type Sharded[T comparable] struct {
+	shards []*Pool[T]
+	next   atomic.Uint64
+	// Shard every borrowed resource came from, so it goes back there.
+	owners sync.Map
+}
+
+// NewSharded creates n pools configured like NewWithOptions does. Capacity
+// given with WithMax is split exactly between them, some shards getting one
+// more resource than others, so sharded pool never owns more resources than
+// capacity allows. WithWarmup, WithMinIdle and WithMaxIdle are split the same
+// way, so they apply to sharded pool as a whole. It panics if n is not
+// positive or capacity is less than n, which would leave shard with none.
+func NewSharded[T comparable](
+	n int,
+	factoryFn func() (T, error),
+	destructorFn func(T),
+	opts ...Option[T],
+) *Sharded[T] {
+	if n <= 0 {
+		panic(fmt.Sprintf("pool: NewSharded: number of shards must be positive, got %d", n))
+	}
+	cfg := defaultConfig[T]()
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.max != -1 && cfg.max < int64(n) {
+		panic(fmt.Sprintf("pool: NewSharded: capacity %d is less than number of shards %d", cfg.max, n))
+	}
+
+	s := &Sharded[T]{shards: make([]*Pool[T], n)}
+	for i := range s.shards {
+		perShard := append([]Option[T](nil), opts...)
+		perShard = append(perShard, func(c *config[T]) {
+			if c.max != -1 {
+				c.max = splitShare(c.max, n, i)
+			}
+			if c.maxIdle != -1 {
+				c.maxIdle = splitShare(c.maxIdle, n, i)
+			}
+			c.minIdle = splitShare(c.minIdle, n, i)
+			c.warmup = int(splitShare(int64(c.warmup), n, i))
+		})
+		s.shards[i] = NewWithOptions(factoryFn, destructorFn, perShard...)
+	}
+	return s
+}
+
+// Returns share of total that i-th of n shards gets, so that shares add up to
+// total exactly.
+func splitShare(total int64, n, i int) int64 {
+	share := total / int64(n)
+	if int64(i) < total%int64(n) {
+		share++
+	}
+	return share
+}
+
+// Get returns resource like Pool.Get does. Idle resource of any shard is
+// preferred to waiting for one.
+func (s *Sharded[T]) Get() (T, error) {
+	return s.GetContext(context.Background())
+}
+
+// GetContext returns resource like Pool.GetContext does.
+func (s *Sharded[T]) GetContext(ctx context.Context) (T, error) {
+	first := int(s.next.Add(1) % uint64(len(s.shards)))
+
+	for i := range s.shards {
+		shard := s.shards[(first+i)%len(s.shards)]
+		resource, ok, err := shard.TryGet()
+		if err != nil {
+			return resource, err
+		}
+		if ok {
+			s.owners.Store(resource, shard)
+			return resource, nil
+		}
+	}
+
+	shard := s.shards[first]
+	resource, err := shard.GetContext(ctx)
+	if err != nil {
+		return resource, err
+	}
+	s.owners.Store(resource, shard)
+	return resource, nil
+}
+
+// Put returns resource to the shard it was borrowed from, resources brought
+// from outside go to any shard. Reports whether resource was accepted like
+// Pool.Put does.
+func (s *Sharded[T]) Put(resource T) bool {
+	if shard, ok := s.owners.LoadAndDelete(resource); ok {
+		return shard.(*Pool[T]).Put(resource)
+	}
+	return s.shards[s.next.Add(1)%uint64(len(s.shards))].Put(resource)
+}
+
+// Cleanup is the same as Close.
+func (s *Sharded[T]) Cleanup() error {
+	return s.Close()
+}
+
+// Close closes every shard like Pool.Close does.
+func (s *Sharded[T]) Close() error {
+	var errs []error
+	for _, shard := range s.shards {
+		errs = append(errs, shard.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// InUse returns number of resources currently borrowed from all shards.
+func (s *Sharded[T]) InUse() int64 {
+	var n int64
+	for _, shard := range s.shards {
+		n += shard.InUse()
+	}
+	return n
+}
+
+// Idle returns number of resources currently stored in all shards.
+func (s *Sharded[T]) Idle() int64 {
+	var n int64
+	for _, shard := range s.shards {
+		n += shard.Idle()
+	}
+	return n
+}
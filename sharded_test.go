@@ -0,0 +1,119 @@
+package pool_test
+
+import (
+	"testing"
+	"time"
+
+	pool "github.com/posidoni/resource-pool"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSharded(t *testing.T) {
+	t.Parallel()
+
+	newSharded := func() *pool.Sharded[*int] {
+		return pool.NewSharded(
+			2,
+			func() (*int, error) {
+				return new(int), nil
+			},
+			func(r *int) {},
+			pool.WithMax[*int](4),
+			pool.WithWait[*int](10*time.Millisecond),
+		)
+	}
+
+	t.Run(
+		"When capacity is split between shards, sharded pool hands out no more than its total capacity",
+		func(t *testing.T) {
+			t.Parallel()
+			p := newSharded()
+			defer p.Close()
+
+			for i := 0; i < 4; i++ {
+				_, err := p.Get()
+				require.NoError(t, err)
+			}
+			_, err := p.Get()
+			require.ErrorIs(t, err, pool.ErrResourceUnavailable)
+			require.Equal(t, int64(4), p.InUse())
+		})
+
+	t.Run(
+		"When objects are put back, they return to the shards they came from",
+		func(t *testing.T) {
+			t.Parallel()
+			p := newSharded()
+			defer p.Close()
+
+			rs := make([]*int, 4)
+			for i := range rs {
+				rs[i], _ = p.Get()
+			}
+			for _, r := range rs {
+				require.True(t, p.Put(r))
+			}
+			require.Equal(t, int64(4), p.Idle())
+			require.False(t, p.Put(new(int)), "Every shard is full")
+		})
+
+	t.Run(
+		"When capacity doesn't divide evenly, sharded pool still hands out no more than its total capacity",
+		func(t *testing.T) {
+			t.Parallel()
+			p := pool.NewSharded(
+				4,
+				func() (*int, error) {
+					return new(int), nil
+				},
+				func(r *int) {},
+				pool.WithMax[*int](10),
+				pool.WithWait[*int](0),
+			)
+			defer p.Close()
+
+			for i := 0; i < 10; i++ {
+				_, err := p.Get()
+				require.NoError(t, err)
+			}
+			_, err := p.Get()
+			require.ErrorIs(t, err, pool.ErrResourceUnavailable)
+			require.Equal(t, int64(10), p.InUse())
+		})
+
+	t.Run(
+		"When warmup is given, it is split between shards rather than repeated by each",
+		func(t *testing.T) {
+			t.Parallel()
+			p := pool.NewSharded(
+				4,
+				func() (*int, error) {
+					return new(int), nil
+				},
+				func(r *int) {},
+				pool.WithMax[*int](10),
+				pool.WithWarmup[*int](6),
+			)
+			defer p.Close()
+
+			require.Equal(t, int64(6), p.Idle())
+		})
+
+	t.Run(
+		"When capacity is less than number of shards, NewSharded panics",
+		func(t *testing.T) {
+			t.Parallel()
+			require.PanicsWithValue(t, "pool: NewSharded: capacity 3 is less than number of shards 4", func() {
+				pool.NewSharded(4, func() (*int, error) { return new(int), nil }, func(r *int) {}, pool.WithMax[*int](3))
+			})
+		})
+
+	t.Run(
+		"When number of shards is not positive, NewSharded panics instead of dividing by zero",
+		func(t *testing.T) {
+			t.Parallel()
+			require.PanicsWithValue(t, "pool: NewSharded: number of shards must be positive, got 0", func() {
+				pool.NewSharded(0, func() (*int, error) { return new(int), nil }, func(r *int) {})
+			})
+		})
+}
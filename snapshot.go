@@ -0,0 +1,66 @@
+package pool
+
+import "time"
+
+// PoolSnapshot is a point-in-time report of pool state, meant to be encoded
+// with encoding/json, e.g. by debug HTTP handler. Durations are reported in
+// milliseconds.
+type PoolSnapshot struct {
+	// When snapshot was taken and when pool was created, according to pool's
+	// clock.
+	Time      time.Time `json:"time"`
+	StartedAt time.Time `json:"started_at"`
+	Closed    bool      `json:"closed"`
+	Draining  bool      `json:"draining"`
+
+	InUse     int64 `json:"in_use"`
+	Idle      int64 `json:"idle"`
+	Waiting   int64 `json:"waiting"`
+	PeakTotal int64 `json:"peak_total"`
+	PeakIdle  int64 `json:"peak_idle"`
+
+	// Configured limits, (-1) means no limit.
+	Max     int64 `json:"max"`
+	MaxIdle int64 `json:"max_idle"`
+	MinIdle int64 `json:"min_idle"`
+	WaitMs  int64 `json:"wait_ms"`
+
+	TotalCreated   int64 `json:"total_created"`
+	TotalDestroyed int64 `json:"total_destroyed"`
+	FastPath       int64 `json:"fast_path"`
+	SlowPath       int64 `json:"slow_path"`
+	TimedOut       int64 `json:"timed_out"`
+	MaxWaitMs      int64 `json:"max_wait_ms"`
+}
+
+// Snapshot returns report of pool state built from Stats along with pool
+// limits, ready to be encoded as JSON.
+func (pool *Pool[T]) Snapshot() PoolSnapshot {
+	s := pool.Stats()
+	snap := PoolSnapshot{
+		Time:           pool.clock.Now(),
+		StartedAt:      pool.startedAt,
+		InUse:          s.InUse,
+		Idle:           s.Idle,
+		Waiting:        s.Waiting,
+		PeakTotal:      s.PeakTotal,
+		PeakIdle:       s.PeakIdle,
+		MinIdle:        pool.minIdle,
+		WaitMs:         pool.waitTime().Milliseconds(),
+		TotalCreated:   s.TotalCreated,
+		TotalDestroyed: s.TotalDestroyed,
+		FastPath:       s.FastPath.Count,
+		SlowPath:       s.SlowPath.Count,
+		TimedOut:       s.TimedOut.Count,
+		MaxWaitMs:      max(s.FastPath.Max, s.SlowPath.Max, s.TimedOut.Max).Milliseconds(),
+	}
+
+	pool.m.Lock()
+	snap.Closed = pool.closed
+	snap.Draining = pool.draining
+	snap.Max = pool.max
+	snap.MaxIdle = pool.maxIdle
+	pool.m.Unlock()
+
+	return snap
+}
@@ -0,0 +1,57 @@
+package pool_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	pool "github.com/posidoni/resource-pool"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshot(t *testing.T) {
+	t.Parallel()
+
+	t.Run(
+		"When snapshot is taken, it reports counts, limits and counters and encodes to JSON",
+		func(t *testing.T) {
+			t.Parallel()
+			clock := newFakeClock()
+			p := pool.New(
+				3,
+				2*time.Second,
+				func() (*int, error) { return new(int), nil },
+				func(r *int) {},
+				false,
+				pool.WithClock[*int](clock),
+				pool.WithMaxIdle[*int](2),
+			)
+			rs, _ := p.GetN(2)
+			p.Put(rs[0])
+			clock.Advance(time.Minute)
+
+			snap := p.Snapshot()
+			require.Equal(t, pool.PoolSnapshot{
+				Time:         time.Unix(0, 0).Add(time.Minute),
+				StartedAt:    time.Unix(0, 0),
+				InUse:        1,
+				Idle:         1,
+				PeakTotal:    2,
+				PeakIdle:     1,
+				Max:          3,
+				MaxIdle:      2,
+				WaitMs:       2000,
+				TotalCreated: 2,
+				FastPath:     2,
+			}, snap)
+
+			encoded, err := json.Marshal(snap)
+			require.NoError(t, err)
+			decoded := map[string]any{}
+			require.NoError(t, json.Unmarshal(encoded, &decoded))
+			require.Equal(t, float64(1), decoded["in_use"])
+			require.Equal(t, float64(3), decoded["max"])
+			require.Equal(t, float64(2000), decoded["wait_ms"])
+			require.Equal(t, false, decoded["closed"])
+		})
+}
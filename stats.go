@@ -0,0 +1,156 @@
+package pool
+
+import (
+	"sync"
+	"time"
+)
+
+// Stats is a point-in-time summary of pool state and activity.
+type Stats struct {
+	// Resources currently borrowed from and stored in the pool.
+	InUse int64
+	Idle  int64
+	// Get calls currently queued up for resource.
+	Waiting int64
+	// Overflow resources currently borrowed, see WithOverflow. They are not
+	// counted in InUse.
+	Overflow int64
+
+	// Most resources pool held at once, borrowed and idle together, and most
+	// idle ones, since pool was created or ResetPeaks was called. PeakTotal
+	// reaching capacity means Get had to wait for resource or fail.
+	PeakTotal int64
+	PeakIdle  int64
+
+	// Cumulative number of resources constructed and destructed by the pool.
+	TotalCreated   int64
+	TotalDestroyed int64
+
+	// Get calls that got resource without waiting: idle one or freshly
+	// constructed one.
+	FastPath WaitStats
+	// Get calls that got resource after waiting for one to be returned.
+	SlowPath WaitStats
+	// Get calls that gave up waiting, either with ErrResourceUnavailable or
+	// because their context was done.
+	TimedOut WaitStats
+}
+
+// WaitStats summarizes how long a kind of Get calls took, from entering Get
+// to getting resource or giving up.
+type WaitStats struct {
+	Count int64
+	Total time.Duration
+	Max   time.Duration
+}
+
+// Stats returns summary of pool state and activity.
+func (pool *Pool[T]) Stats() Stats {
+	s := Stats{
+		TotalCreated:   pool.TotalCreated(),
+		TotalDestroyed: pool.TotalDestroyed(),
+	}
+
+	pool.m.Lock()
+	s.InUse = pool.objsInUse - int64(len(pool.idle))
+	s.Idle = int64(len(pool.idle))
+	s.Waiting = int64(len(pool.waiters))
+	s.Overflow = pool.overflowed
+	s.PeakTotal = pool.peakTotal
+	s.PeakIdle = pool.peakIdle
+	pool.m.Unlock()
+
+	pool.waits.m.Lock()
+	s.FastPath = pool.waits.byKind[waitFast]
+	s.SlowPath = pool.waits.byKind[waitSlow]
+	s.TimedOut = pool.waits.byKind[waitTimedOut]
+	pool.waits.m.Unlock()
+
+	return s
+}
+
+// Utilization returns share of pool capacity that is borrowed, from 0 to 1,
+// e.g. to drive autoscaling. Capacity of unlimited pool is whatever it owns
+// at the moment, so its utilization is share of owned resources that are
+// borrowed: 1 means no resource is idle and every Get has to construct one,
+// pool that owns nothing reports 0. Borrowed resources may exceed capacity
+// lowered by SetMax, then utilization is above 1, while pool of zero capacity
+// is always fully utilized. Under WithWeight, shares are shares of weight.
+func (pool *Pool[T]) Utilization() float64 {
+	pool.m.Lock()
+	defer pool.m.Unlock()
+
+	borrowed := pool.weight
+	for _, e := range pool.idle {
+		borrowed -= pool.weigh(e.value)
+	}
+	capacity := pool.max
+	if capacity == -1 {
+		capacity = pool.weight
+	}
+	if capacity == 0 {
+		if pool.max == 0 {
+			return 1
+		}
+		return 0
+	}
+	return float64(borrowed) / float64(capacity)
+}
+
+// ResetPeaks restarts tracking of Stats.PeakTotal and Stats.PeakIdle from
+// current counts.
+func (pool *Pool[T]) ResetPeaks() {
+	pool.m.Lock()
+	defer pool.m.Unlock()
+
+	pool.peakTotal = 0
+	pool.peakIdle = 0
+	pool.notePeaks()
+}
+
+// Raises high-water marks to current counts. Must be called with pool.m held
+// whenever they grow.
+func (pool *Pool[T]) notePeaks() {
+	pool.peakTotal = max(pool.peakTotal, pool.objsInUse)
+	pool.peakIdle = max(pool.peakIdle, int64(len(pool.idle)))
+}
+
+// TotalCreated returns number of resources constructed by the pool since it
+// was created.
+func (pool *Pool[T]) TotalCreated() int64 {
+	return pool.totalCreated.Load()
+}
+
+// TotalDestroyed returns number of resources destructed by the pool since it
+// was created.
+func (pool *Pool[T]) TotalDestroyed() int64 {
+	return pool.totalDestroyed.Load()
+}
+
+type waitKind int
+
+const (
+	waitNone waitKind = iota - 1
+	waitFast
+	waitSlow
+	waitTimedOut
+)
+
+// Accumulates WaitStats of every kind. Has its own lock, so that recording
+// doesn't contend with pool lock.
+type waitStats struct {
+	m      sync.Mutex
+	byKind [3]WaitStats
+}
+
+func (w *waitStats) record(kind waitKind, d time.Duration) {
+	w.m.Lock()
+	defer w.m.Unlock()
+
+	s := &w.byKind[kind]
+	s.Count++
+	s.Total += d
+	if d > s.Max {
+		s.Max = d
+	}
+}
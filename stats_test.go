@@ -0,0 +1,167 @@
+package pool_test
+
+import (
+	"testing"
+	"time"
+
+	pool "github.com/posidoni/resource-pool"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTotals(t *testing.T) {
+	t.Parallel()
+
+	t.Run(
+		"When objects are constructed and destructed in different places, pool counts all of them",
+		func(t *testing.T) {
+			t.Parallel()
+			p := pool.New(
+				3,
+				10*time.Millisecond,
+				func() (*int, error) {
+					return new(int), nil
+				},
+				func(r *int) {},
+				true,
+				pool.WithMaxIdle[*int](1),
+			)
+			r1, _ := p.Get()
+			r2, _ := p.Get()
+			r3, _ := p.Get()
+			require.Equal(t, int64(3), p.TotalCreated())
+
+			p.Put(r1)
+			p.Put(r2) // over idle limit
+			require.Equal(t, int64(1), p.TotalDestroyed())
+
+			p.SetMax(1) // idle r1 doesn't fit
+			require.Equal(t, int64(2), p.TotalDestroyed())
+			require.True(t, p.Put(r3))
+
+			p.Cleanup()
+			require.Equal(t, int64(3), p.TotalCreated())
+			require.Equal(t, int64(3), p.TotalDestroyed())
+		})
+}
+
+func TestWaitStats(t *testing.T) {
+	t.Parallel()
+
+	t.Run(
+		"When Get calls are served in different ways, pool records their wait times separately",
+		func(t *testing.T) {
+			t.Parallel()
+			p := pool.New(
+				1,
+				20*time.Millisecond,
+				func() (*int, error) {
+					return new(int), nil
+				},
+				func(r *int) {},
+				true,
+			)
+			r, _ := p.Get() // fast
+			_, _ = p.Get()  // times out
+			go func() {
+				time.Sleep(10 * time.Millisecond)
+				p.Put(r)
+			}()
+			_, err := p.GetTimeout(time.Second) // slow
+			require.NoError(t, err)
+
+			s := p.Stats()
+			require.Equal(t, int64(1), s.FastPath.Count)
+			require.Equal(t, int64(1), s.SlowPath.Count)
+			require.Equal(t, int64(1), s.TimedOut.Count)
+			require.GreaterOrEqual(t, s.TimedOut.Max, 20*time.Millisecond)
+			require.GreaterOrEqual(t, s.SlowPath.Total, 10*time.Millisecond)
+			require.Equal(t, int64(1), s.InUse)
+			require.Equal(t, int64(1), s.TotalCreated)
+		})
+}
+
+func TestPeaks(t *testing.T) {
+	t.Parallel()
+
+	t.Run(
+		"When load goes up and down, pool remembers high-water marks until they are reset",
+		func(t *testing.T) {
+			t.Parallel()
+			p := pool.New(
+				4,
+				10*time.Millisecond,
+				func() (*int, error) {
+					return new(int), nil
+				},
+				func(r *int) {},
+				true,
+			)
+			rs, err := p.GetN(3)
+			require.NoError(t, err)
+			p.PutN(rs)
+			r, _ := p.Get()
+
+			s := p.Stats()
+			require.Equal(t, int64(3), s.PeakTotal)
+			require.Equal(t, int64(3), s.PeakIdle)
+			require.Equal(t, int64(2), s.Idle)
+
+			p.Discard(r)
+			p.ResetPeaks()
+			s = p.Stats()
+			require.Equal(t, int64(2), s.PeakTotal, "Peaks restart from current counts")
+			require.Equal(t, int64(2), s.PeakIdle)
+
+			_, err = p.GetN(4)
+			require.NoError(t, err)
+			s = p.Stats()
+			require.Equal(t, int64(4), s.PeakTotal, "Capacity was hit")
+			require.Equal(t, int64(2), s.PeakIdle)
+		})
+}
+
+func TestUtilization(t *testing.T) {
+	t.Parallel()
+
+	t.Run(
+		"When pool is bounded, utilization is share of capacity that is borrowed",
+		func(t *testing.T) {
+			t.Parallel()
+			p := pool.New(
+				4,
+				10*time.Millisecond,
+				func() (*int, error) {
+					return new(int), nil
+				},
+				func(r *int) {},
+				false,
+			)
+			require.Zero(t, p.Utilization())
+			rs, _ := p.GetN(3)
+			p.Put(rs[0])
+			require.InDelta(t, 0.5, p.Utilization(), 1e-9)
+
+			p.SetMax(1)
+			require.InDelta(t, 2.0, p.Utilization(), 1e-9, "Borrowed objects exceed lowered capacity")
+		})
+
+	t.Run(
+		"When pool is unlimited, utilization is share of owned objects that are borrowed",
+		func(t *testing.T) {
+			t.Parallel()
+			p := pool.New(
+				-1,
+				10*time.Millisecond,
+				func() (*int, error) {
+					return new(int), nil
+				},
+				func(r *int) {},
+				false,
+			)
+			require.Zero(t, p.Utilization())
+			rs, _ := p.GetN(4)
+			require.InDelta(t, 1.0, p.Utilization(), 1e-9)
+			p.Put(rs[0])
+			require.InDelta(t, 0.75, p.Utilization(), 1e-9)
+		})
+}
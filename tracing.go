@@ -0,0 +1,30 @@
+package pool
+
+import (
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Span attributes set by WithTracer.
+const (
+	AttrWaitTime = attribute.Key("pool.wait_time_ms")
+	AttrFresh    = attribute.Key("pool.fresh")
+	AttrTimedOut = attribute.Key("pool.timed_out")
+)
+
+// Ends span started by Get.
+func endSpan(span trace.Span, wait time.Duration, fresh, timedOut bool, err error) {
+	span.SetAttributes(
+		AttrWaitTime.Float64(float64(wait)/float64(time.Millisecond)),
+		AttrFresh.Bool(fresh),
+		AttrTimedOut.Bool(timedOut),
+	)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
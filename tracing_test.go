@@ -0,0 +1,101 @@
+package pool_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	pool "github.com/posidoni/resource-pool"
+)
+
+func TestTracer(t *testing.T) {
+	t.Parallel()
+
+	attrs := func(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+		m := map[attribute.Key]attribute.Value{}
+		for _, kv := range span.Attributes() {
+			m[kv.Key] = kv.Value
+		}
+		return m
+	}
+
+	t.Run(
+		"When tracer is given, every Get ends its span, including timed out ones",
+		func(t *testing.T) {
+			t.Parallel()
+			recorder := tracetest.NewSpanRecorder()
+			tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+			p := pool.New(
+				1,
+				time.Millisecond,
+				func() (int, error) {
+					return 0, nil
+				},
+				func(r int) {},
+				true,
+				pool.WithTracer[int](tracer),
+			)
+
+			r, err := p.Get()
+			require.NoError(t, err)
+			_, err = p.Get()
+			require.ErrorIs(t, err, pool.ErrResourceUnavailable)
+			p.Put(r)
+			_, err = p.Get()
+			require.NoError(t, err)
+
+			spans := recorder.Ended()
+			require.Len(t, spans, 3)
+			for _, span := range spans {
+				require.Equal(t, "pool.Get", span.Name())
+			}
+
+			fresh := attrs(spans[0])
+			require.True(t, fresh[pool.AttrFresh].AsBool())
+			require.False(t, fresh[pool.AttrTimedOut].AsBool())
+			require.Equal(t, codes.Unset, spans[0].Status().Code)
+
+			timedOut := attrs(spans[1])
+			require.True(t, timedOut[pool.AttrTimedOut].AsBool())
+			require.GreaterOrEqual(t, timedOut[pool.AttrWaitTime].AsFloat64(), 1.0)
+			require.Equal(t, codes.Error, spans[1].Status().Code)
+
+			idle := attrs(spans[2])
+			require.False(t, idle[pool.AttrFresh].AsBool())
+		})
+
+	t.Run(
+		"When pinned object is handed out, GetPinned ends its span as Get does",
+		func(t *testing.T) {
+			t.Parallel()
+			recorder := tracetest.NewSpanRecorder()
+			tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+			p := pool.New(
+				1,
+				time.Millisecond,
+				func() (*int, error) {
+					return new(int), nil
+				},
+				func(r *int) {},
+				true,
+				pool.WithTracer[*int](tracer),
+			)
+
+			r, err := p.GetPinned("worker")
+			require.NoError(t, err)
+			p.PutPinned("worker", r)
+			pinned, err := p.GetPinned("worker")
+			require.NoError(t, err)
+			require.Same(t, r, pinned)
+
+			spans := recorder.Ended()
+			require.Len(t, spans, 2)
+			require.Equal(t, "pool.Get", spans[1].Name())
+			require.False(t, attrs(spans[1])[pool.AttrFresh].AsBool())
+		})
+}